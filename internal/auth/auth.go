@@ -0,0 +1,224 @@
+// Package auth checks the credentials of all platforms enabled, and runs their
+// login flows.
+package auth
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/platforms"
+	"codeberg.org/snonux/gos/internal/platforms/linkedin"
+	"codeberg.org/snonux/gos/internal/platforms/linkedin/oauth2"
+	"codeberg.org/snonux/gos/internal/platforms/mastodon"
+	"codeberg.org/snonux/gos/internal/table"
+)
+
+const checkTimeout = 10 * time.Second
+
+// Tokens expiring within this time are reported.
+const expiryWarning = 7 * 24 * time.Hour
+
+// ErrProblems is returned by Status if any credentials are broken, so that gos
+// exits non-zero.
+var ErrProblems = errors.New("credential problems found")
+
+// The states of a check.
+const (
+	stateOK      = "ok"
+	stateUnknown = "unknown"
+	stateWarning = "warning"
+	stateError   = "error"
+)
+
+// check is the result of a single credential check of a platform.
+type check struct {
+	platform, name, state, detail string
+}
+
+// Status checks the credentials of all platforms enabled and prints the results.
+func Status(ctx context.Context, args config.Args) error {
+	var (
+		checks      []check
+		linkedInSet bool
+	)
+	for _, platformStr := range slices.Sorted(maps.Keys(args.Platforms)) {
+		platform, err := platforms.New(platformStr)
+		if err != nil {
+			return err
+		}
+		switch network := config.Network(platform.String()); {
+		case network == "mastodon":
+			checks = append(checks, checkMastodon(ctx, args.Config)...)
+		case network == "linkedin":
+			// The organisation pages are posted to with the token of the member.
+			if !linkedInSet {
+				checks = append(checks, checkLinkedIn(ctx, args.Config)...)
+				linkedInSet = true
+			}
+			if org, isOrg := strings.CutPrefix(platform.String(), config.LinkedInOrgPrefix); isOrg {
+				checks = append(checks, checkLinkedInOrg(args.Config, platform.String(), org))
+			}
+		}
+	}
+	if len(checks) == 0 {
+		colour.Infoln("No platforms with credentials enabled")
+		return nil
+	}
+
+	tab := table.New().Header("Platform", "Check", "Status", "Details")
+	var problems bool
+	for _, c := range checks {
+		tab.Row(c.platform, c.name, c.state, c.detail)
+		problems = problems || c.state == stateError
+	}
+	if err := tab.Render(); err != nil {
+		return err
+	}
+	if problems {
+		return ErrProblems
+	}
+	return nil
+}
+
+func checkMastodon(ctx context.Context, conf config.Config) []check {
+	newCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	creds, err := mastodon.VerifyCredentials(newCtx, conf)
+	if err != nil {
+		return []check{{"mastodon", "credentials", stateError, fmt.Sprintf("%v, run 'gos auth login mastodon INSTANCE'", err)}}
+	}
+	checks := []check{{"mastodon", "credentials", stateOK, creds.Account}}
+	switch {
+	case len(creds.Scopes) == 0:
+		checks = append(checks, check{"mastodon", "scopes", stateUnknown, "not told by the instance"})
+	case slices.Contains(creds.Scopes, "write") || slices.Contains(creds.Scopes, "write:statuses"):
+		checks = append(checks, check{"mastodon", "scopes", stateOK, strings.Join(creds.Scopes, " ")})
+	default:
+		checks = append(checks, check{"mastodon", "scopes", stateError, "write:statuses missing in " + strings.Join(creds.Scopes, " ")})
+	}
+	return checks
+}
+
+func checkLinkedIn(ctx context.Context, conf config.Config) []check {
+	if conf.LinkedInAccessToken == "" {
+		return []check{{"linkedin", "credentials", stateError, "not logged in, run 'gos auth login linkedin'"}}
+	}
+	var checks []check
+	personCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	if personID, err := oauth2.PersonID(personCtx, conf.LinkedInAccessToken); err != nil {
+		state := stateError
+		if oauth2.CanRefresh(conf) {
+			state = stateWarning
+		}
+		checks = append(checks, check{"linkedin", "credentials", state, err.Error()})
+	} else {
+		checks = append(checks, check{"linkedin", "credentials", stateOK, "urn:li:person:" + personID})
+	}
+
+	checks = append(checks, expiryCheck("access token", conf.LinkedInTokenExpiry, oauth2.CanRefresh(conf)))
+	if conf.LinkedInRefreshToken == "" {
+		checks = append(checks, check{"linkedin", "refresh token", stateWarning, "none, gos has to be authorised in the browser again once the access token expires"})
+	} else {
+		checks = append(checks, expiryCheck("refresh token", conf.LinkedInRefreshTokenExpiry, false))
+	}
+
+	newCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	if info, err := oauth2.Introspect(newCtx, conf); err != nil {
+		checks = append(checks, check{"linkedin", "scopes", stateUnknown, err.Error()})
+	} else {
+		var missing []string
+		for _, scope := range oauth2.Scopes(conf) {
+			if !slices.Contains(info.Scopes, scope) {
+				missing = append(missing, scope)
+			}
+		}
+		if len(missing) > 0 {
+			checks = append(checks, check{"linkedin", "scopes", stateError,
+				fmt.Sprintf("%s missing, run 'gos auth login linkedin'", strings.Join(missing, " "))})
+		} else {
+			checks = append(checks, check{"linkedin", "scopes", stateOK, strings.Join(info.Scopes, " ")})
+		}
+	}
+
+	if version, err := linkedin.CheckVersion(newCtx, conf.LinkedInAccessToken, conf.LinkedInVersion); errors.Is(err, linkedin.ErrVersionUnknown) {
+		checks = append(checks, check{"linkedin", "API version", stateUnknown, version + ": " + err.Error()})
+	} else if err != nil {
+		checks = append(checks, check{"linkedin", "API version", stateError, err.Error()})
+	} else {
+		checks = append(checks, check{"linkedin", "API version", stateOK, version + " is active"})
+	}
+	return checks
+}
+
+// Checks the expiry of a token. An expired access token is only a warning, if it
+// can be refreshed.
+func expiryCheck(name string, expiry int64, refreshable bool) check {
+	if expiry == 0 {
+		return check{"linkedin", name, stateUnknown, "expiry not known"}
+	}
+	expires := time.Unix(expiry, 0)
+	detail := "expires " + expires.Format(time.DateTime)
+	switch until := time.Until(expires); {
+	case until <= 0 && refreshable:
+		return check{"linkedin", name, stateWarning, "expired " + expires.Format(time.DateTime) + ", will be refreshed"}
+	case until <= 0:
+		return check{"linkedin", name, stateError, "expired " + expires.Format(time.DateTime)}
+	case until < expiryWarning:
+		return check{"linkedin", name, stateWarning, detail}
+	default:
+		return check{"linkedin", name, stateOK, detail}
+	}
+}
+
+func checkLinkedInOrg(conf config.Config, platform, org string) check {
+	orgID, ok := conf.LinkedInOrg(org)
+	if !ok {
+		return check{platform, "organisation", stateError, fmt.Sprintf("'%s' not configured in LinkedInOrgs", org)}
+	}
+	return check{platform, "organisation", stateOK, "urn:li:organization:" + orgID}
+}
+
+// Login runs the login flow of the platform: "linkedin [-code CODE]" or
+// "mastodon [INSTANCE]".
+func Login(ctx context.Context, args config.Args, loginArgs []string) error {
+	if len(loginArgs) == 0 {
+		return errors.New("no platform given, e.g. linkedin or mastodon")
+	}
+	platform, err := platforms.New(loginArgs[0])
+	if err != nil {
+		return err
+	}
+	switch config.Network(platform.String()) {
+	case "linkedin":
+		flags := flag.NewFlagSet("auth login linkedin", flag.ContinueOnError)
+		code := flags.String("code", "", "The code, or the URL redirected to, of a headless authorisation")
+		if err := flags.Parse(loginArgs[1:]); err != nil {
+			return err
+		}
+		if *code != "" {
+			return oauth2.AuthorizeWithCode(ctx, args, *code)
+		}
+		return oauth2.Authorize(ctx, args)
+	case "mastodon":
+		instance := args.Config.MastodonURL
+		if len(loginArgs) > 1 {
+			instance = loginArgs[1]
+		}
+		if instance == "" {
+			return errors.New("no Mastodon instance given, e.g. 'gos auth login mastodon fosstodon.org'")
+		}
+		return mastodon.Login(ctx, args, instance)
+	default:
+		return fmt.Errorf("no login for platform %s", platform)
+	}
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/config"
+)
+
+func TestExpiryCheck(t *testing.T) {
+	table := []struct {
+		expiry      time.Duration
+		refreshable bool
+		state       string
+	}{
+		{30 * 24 * time.Hour, false, stateOK},
+		{24 * time.Hour, false, stateWarning},
+		{-time.Hour, true, stateWarning},
+		{-time.Hour, false, stateError},
+	}
+	for _, tc := range table {
+		c := expiryCheck("access token", time.Now().Add(tc.expiry).Unix(), tc.refreshable)
+		if c.state != tc.state {
+			t.Errorf("expected %s for an expiry in %v (refreshable=%v) but got %s: %s",
+				tc.state, tc.expiry, tc.refreshable, c.state, c.detail)
+		}
+	}
+	if c := expiryCheck("access token", 0, false); c.state != stateUnknown {
+		t.Errorf("expected an unknown expiry but got %s", c.state)
+	}
+}
+
+func TestCheckLinkedInOrg(t *testing.T) {
+	conf := config.Config{LinkedInOrgs: map[string]string{"acme": "1234"}}
+	if c := checkLinkedInOrg(conf, "linkedinorg-acme", "acme"); c.state != stateOK {
+		t.Errorf("expected acme to be configured but got %s: %s", c.state, c.detail)
+	}
+	if c := checkLinkedInOrg(conf, "linkedinorg-foo", "foo"); c.state != stateError {
+		t.Errorf("expected foo not to be configured but got %s: %s", c.state, c.detail)
+	}
+}
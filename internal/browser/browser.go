@@ -0,0 +1,27 @@
+// Package browser opens URLs in the browser of the user, e.g. for the OAuth2
+// authorisations.
+package browser
+
+import (
+	"os/exec"
+	"runtime"
+
+	"codeberg.org/snonux/gos/internal/colour"
+)
+
+// Open opens the URL in the browser, e.g. firefox.
+func Open(browser, url string) error {
+	colour.Infoln("Opening", url, "in", browser)
+	switch runtime.GOOS {
+	case "windows":
+		cmd := exec.Command("cmd", "/C", "start", browser, url)
+		return cmd.Start()
+	case "darwin":
+		cmd := exec.Command("open", "-a", browser, url)
+		return cmd.Start()
+	default:
+		// Linux and other Unix like (e.g. *BSDs)
+		cmd := exec.Command(browser, url)
+		return cmd.Start()
+	}
+}
@@ -0,0 +1,284 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/auth"
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/journal"
+	"codeberg.org/snonux/gos/internal/lint"
+	"codeberg.org/snonux/gos/internal/queue"
+	"codeberg.org/snonux/gos/internal/secrets"
+	"codeberg.org/snonux/gos/internal/table"
+	"codeberg.org/snonux/gos/internal/trash"
+)
+
+// errUsage is returned by a command when invoked with invalid arguments.
+var errUsage = errors.New("invalid command arguments")
+
+// errLintErrors is returned by the lint command, so that gos exits non-zero.
+var errLintErrors = errors.New("lint errors found")
+
+// command is a gos sub-command, e.g. "gos requeue foo.txt".
+type command struct {
+	usage   string
+	minArgs int
+	// The command needs the secrets, so the encrypted credential store is unlocked.
+	secrets bool
+	run     func(ctx context.Context, args config.Args, cmdArgs []string) error
+}
+
+var commands = map[string]command{
+	"retag": {
+		usage:   "retag NAME [+TAG|-TAG]... - Add or remove tags (prio, now, ask, share:...) on all copies of an entry",
+		minArgs: 2,
+		run: func(ctx context.Context, args config.Args, cmdArgs []string) error {
+			var add, remove []string
+			for _, tag := range cmdArgs[1:] {
+				switch {
+				case strings.HasPrefix(tag, "+"):
+					add = append(add, tag[1:])
+				case strings.HasPrefix(tag, "-"):
+					remove = append(remove, tag[1:])
+				default:
+					return fmt.Errorf("tag '%s' must be prefixed with + or -", tag)
+				}
+			}
+			return queue.Retag(args, cmdArgs[0], add, remove)
+		},
+	},
+	"prio": {
+		usage:   "prio NAME now|prio|soon|none - Reprioritise an entry on all platforms",
+		minArgs: 2,
+		run: func(ctx context.Context, args config.Args, cmdArgs []string) error {
+			return queue.Reprioritise(args, cmdArgs[0], cmdArgs[1])
+		},
+	},
+	"requeue": {
+		usage:   "requeue NAME [PLATFORM]... - Move a posted entry back to queued for a repost",
+		minArgs: 1,
+		run: func(ctx context.Context, args config.Args, cmdArgs []string) error {
+			return queue.Requeue(args, cmdArgs[0], cmdArgs[1:])
+		},
+	},
+	"unqueue": {
+		usage:   "unqueue NAME PLATFORM... - Remove a queued entry from the given platforms only",
+		minArgs: 2,
+		run: func(ctx context.Context, args config.Args, cmdArgs []string) error {
+			return queue.Unqueue(args, cmdArgs[0], cmdArgs[1:])
+		},
+	},
+	"trash": {
+		usage:   "trash list|restore NAME - List the trashbin or restore an entry from it",
+		minArgs: 1,
+		run: func(ctx context.Context, args config.Args, cmdArgs []string) error {
+			switch {
+			case cmdArgs[0] == "list":
+				return listTrash(args)
+			case cmdArgs[0] == "restore" && len(cmdArgs) > 1:
+				_, err := trash.New(args).Restore(cmdArgs[1])
+				return err
+			default:
+				return errUsage
+			}
+		},
+	},
+	"watch": {
+		usage: "watch - Watch the gosDir and queue new entries as soon as they stop changing",
+		run: func(ctx context.Context, args config.Args, cmdArgs []string) error {
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return queue.Watch(ctx, args)
+		},
+	},
+	"undo": {
+		usage: "undo [N] - Undo the last N (default 1) local state transitions recorded in the journal",
+		run: func(ctx context.Context, args config.Args, cmdArgs []string) error {
+			n, err := countArg(cmdArgs, 1)
+			if err != nil {
+				return err
+			}
+			if args.DryRun {
+				ops, err := journal.Read(journal.Path(args.GosDir))
+				if err != nil {
+					return err
+				}
+				ops = journal.Undoable(ops)
+				for _, op := range ops[:min(n, len(ops))] {
+					colour.Infoln("Not undoing", op, "as dry-run mode enabled")
+				}
+				return nil
+			}
+			undone, err := journal.UndoLast(args.GosDir, n)
+			colour.Infoln("Undone", len(undone), "operation(s)")
+			return err
+		},
+	},
+	"journal": {
+		usage: "journal [N] - List the last N (default 20) not yet undone operations of the journal",
+		run: func(ctx context.Context, args config.Args, cmdArgs []string) error {
+			n, err := countArg(cmdArgs, 20)
+			if err != nil {
+				return err
+			}
+			ops, err := journal.Read(journal.Path(args.GosDir))
+			if err != nil {
+				return err
+			}
+			ops = journal.Undoable(ops)
+			if len(ops) == 0 {
+				colour.Infoln("Nothing recorded in the journal")
+				return nil
+			}
+			tab := table.New().Header("Seq", "Time", "Operation", "From", "To")
+			for _, op := range ops[:min(n, len(ops))] {
+				tab.Row(op.Seq, op.Time.Format(time.DateTime), string(op.Kind), op.From, op.To)
+			}
+			return tab.Render()
+		},
+	},
+	"lint": {
+		usage:   "lint - Check all inboxed and queued entries for problems, e.g. over-limit content or broken links",
+		minArgs: 0,
+		run: func(ctx context.Context, args config.Args, cmdArgs []string) error {
+			report, err := lint.All(ctx, args)
+			if err != nil {
+				return err
+			}
+			if err := report.Render(); err != nil {
+				return err
+			}
+			if report.HasErrors() {
+				return errLintErrors
+			}
+			return nil
+		},
+	},
+	"migrate": {
+		usage:   "migrate - Move the file name tags of all inboxed and queued entries into their front matter",
+		minArgs: 0,
+		run: func(ctx context.Context, args config.Args, cmdArgs []string) error {
+			return queue.Migrate(args)
+		},
+	},
+	"auth": {
+		usage:   "auth status|login linkedin [-code CODE|URL]|login mastodon [INSTANCE] - Check the credentials of all platforms, or log in to a platform",
+		minArgs: 1,
+		secrets: true,
+		run: func(ctx context.Context, args config.Args, cmdArgs []string) error {
+			switch cmdArgs[0] {
+			case "status":
+				return auth.Status(ctx, args)
+			case "login":
+				return auth.Login(ctx, args, cmdArgs[1:])
+			case "linkedin":
+				// Short for "auth login linkedin", e.g. "auth linkedin -code CODE".
+				return auth.Login(ctx, args, cmdArgs)
+			default:
+				return errUsage
+			}
+		},
+	},
+	"secrets": {
+		usage:   "secrets set NAME [VALUE]|get NAME|list|rotate [-keyFile PATH] - Manage the encrypted credential store",
+		minArgs: 1,
+		secrets: true,
+		run: func(ctx context.Context, args config.Args, cmdArgs []string) error {
+			switch cmdArgs[0] {
+			case "set":
+				return secrets.Set(args, cmdArgs[1:])
+			case "get":
+				if len(cmdArgs) != 2 {
+					return errUsage
+				}
+				return secrets.Get(args, cmdArgs[1])
+			case "list":
+				return secrets.List(args)
+			case "rotate":
+				return secrets.Rotate(args, cmdArgs[1:])
+			default:
+				return errUsage
+			}
+		},
+	},
+	"inbox": {
+		usage:   "inbox NAME - Pull a queued entry back to the inbox for editing",
+		minArgs: 1,
+		run: func(ctx context.Context, args config.Args, cmdArgs []string) error {
+			return queue.Inbox(args, cmdArgs[0])
+		},
+	},
+}
+
+func runCommand(ctx context.Context, args config.Args, cmdArgs []string) error {
+	cmd, ok := commands[cmdArgs[0]]
+	if !ok {
+		printCommands()
+		return fmt.Errorf("no such command '%s'", cmdArgs[0])
+	}
+	if len(cmdArgs)-1 < cmd.minArgs {
+		return fmt.Errorf("%w, usage: gos %s", errUsage, cmd.usage)
+	}
+	if cmd.secrets {
+		if err := args.Config.UnlockStore(args.ConfigPath); err != nil {
+			return err
+		}
+	}
+	err := cmd.run(ctx, args, cmdArgs[1:])
+	if errors.Is(err, errUsage) {
+		return fmt.Errorf("%w, usage: gos %s", err, cmd.usage)
+	}
+	return err
+}
+
+// countArg parses the optional count argument of a command.
+func countArg(cmdArgs []string, defaultCount int) (int, error) {
+	if len(cmdArgs) == 0 {
+		return defaultCount, nil
+	}
+	n, err := strconv.Atoi(cmdArgs[0])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%w: invalid count '%s'", errUsage, cmdArgs[0])
+	}
+	return n, nil
+}
+
+func listTrash(args config.Args) error {
+	items, err := trash.New(args).List()
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		colour.Infoln("The trashbin is empty")
+		return nil
+	}
+	tab := table.New().Header("Trashed", "Platform", "Name", "Restores to")
+	for _, it := range items {
+		tab.Row(it.Trashed.Format(time.DateTime), it.Platform(), it.Name(), it.OriginPath)
+	}
+	return tab.Render()
+}
+
+func printCommands() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tab := table.New().Header("Gos commands")
+	for _, name := range names {
+		tab.Row("gos " + commands[name].usage)
+	}
+	tab.MustRender()
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -8,27 +9,51 @@ import (
 	"codeberg.org/snonux/gos/internal/colour"
 )
 
+// ContentFilter rewrites the content of an entry with the tags for a platform, e.g.
+// to append hashtags.
+type ContentFilter func(tags []string, platform, content string) string
+
 type Args struct {
-	GosDir           string
-	CacheDir         string
-	DryRun           bool
-	Platforms        map[string]int // Platform and post size limits
-	Target           int
-	MinQueued        int
-	MaxDaysQueued    int
-	PauseDays        int
-	RunInterval      time.Duration
-	Lookback         time.Duration
-	ConfigPath       string
-	Config           Config
-	OAuth2Browser    string
-	GeminiSummaryFor []string
-	GemtexterEnable  bool
-	GeminiCapsules   []string
-	ComposeMode      bool
-	StatsOnly        bool
+	GosDir         string
+	CacheDir       string
+	PreviewTTL     time.Duration // How long to cache link previews
+	DryRun         bool
+	Platforms      map[string]int // Platform and post size limits
+	Target         int
+	MinQueued      int
+	MaxDaysQueued  int
+	PauseDays      int
+	RunInterval    time.Duration
+	Lookback       time.Duration
+	TrashRetention time.Duration // How long to keep items in the trashbin
+	LintBlock      bool          // Keep entries with lint errors in the inbox
+	// Duplicates of entries queued or posted within the window are reported.
+	DuplicateWindow    time.Duration
+	DuplicateThreshold float64 // Similarity from which on entries are near duplicates
+	// Similarity from which on entries sharing a link are duplicates.
+	DuplicateLinkThreshold float64
+	DuplicateRefuse        bool // Keep duplicates in the inbox instead of asking
+	// Recycling policy of posted evergreen entries.
+	EvergreenMinAge     time.Duration // Minimum age since the last post
+	EvergreenMaxReposts int           // Maximum number of reposts
+	EvergreenShare      float64       // Share of the weekly target recycled posts may use
+	SeriesReply         bool          // Reply to the previous part of a series (Mastodon)
+	ConfigPath          string
+	Config              Config
+	OAuth2Browser       string
+	OAuth2Headless      bool // Authorise without a local browser, e.g. over SSH
+	GeminiSummaryFor    []string
+	GemtexterEnable     bool
+	GeminiCapsules      []string
+	ComposeMode         bool
+	StatsOnly           bool
+	// Applied in order to the content posted to every platform.
+	Filters []ContentFilter
 }
 
+// ParsePlatforms parses the enabled platforms and their size limits, e.g.
+// "Mastodon:500,LinkedInOrg:acme:1000". LinkedIn organisation pages must be
+// configured in LinkedInOrgs of the config, so the config must be loaded first.
 func (a *Args) ParsePlatforms(platformStrs string) error {
 	a.Platforms = make(map[string]int)
 
@@ -36,6 +61,16 @@ func (a *Args) ParsePlatforms(platformStrs string) error {
 		// E.g. Mastodon:500
 		parts := strings.Split(platformInfo, ":")
 		platformStr := parts[0]
+		// E.g. LinkedInOrg:acme:1000 is the platform LinkedInOrg-acme
+		if strings.EqualFold(platformStr, strings.TrimSuffix(LinkedInOrgPrefix, "-")) && len(parts) > 1 {
+			platformStr = fmt.Sprintf("%s-%s", platformStr, parts[1])
+			parts = parts[1:]
+		}
+		if org, ok := strings.CutPrefix(strings.ToLower(platformStr), LinkedInOrgPrefix); ok {
+			if _, ok := a.Config.LinkedInOrg(org); !ok {
+				return fmt.Errorf("no LinkedIn organisation '%s' configured in LinkedInOrgs", org)
+			}
+		}
 
 		// E.g. args.Platform["mastodon"] = 500
 		if len(parts) > 1 {
@@ -28,9 +28,66 @@ type Config struct {
 	LinkedInAccessToken string `json:"LinkedInAccessToken,omitempty"`
 	// Will be updated by gos automatically, after successful oauth2
 	LinkedInPersonID string `json:"LinkedInPersonID,omitempty"`
+	// Will be updated by gos automatically, after successful oauth2. Used to
+	// refresh the access token without user interaction.
+	LinkedInRefreshToken string `json:"LinkedInRefreshToken,omitempty"`
+	// Unix epochs when the access and the refresh tokens expire, updated automatically
+	LinkedInTokenExpiry        int64 `json:"LinkedInTokenExpiry,omitempty"`
+	LinkedInRefreshTokenExpiry int64 `json:"LinkedInRefreshTokenExpiry,omitempty"`
+	// LinkedIn organisation pages to post to, e.g. {"acme": "12345"}. Each one is
+	// the platform LinkedInOrg-NAME, posting as urn:li:organization:ID.
+	LinkedInOrgs map[string]string `json:"LinkedInOrgs,omitempty"`
 	// Pause posting between these dates (format: "2006-01-02")
 	PauseStart string `json:"PauseStart,omitempty"`
 	PauseEnd   string `json:"PauseEnd,omitempty"`
+	// Hashtags appended, rewritten and limited per platform
+	Hashtags Hashtags `json:"Hashtags,omitempty"`
+	// Rules rewriting the links posted, applied in order
+	Links []LinkRule `json:"Links,omitempty"`
+
+	// The secret references the config file was loaded with
+	refs map[string]secretRef
+	// The encrypted credential store, if any
+	store *Store
+}
+
+// LinkRule rewrites the links posted, e.g. to add utm_source=mastodon to the links
+// to the own blog. Platform names are lower case, e.g. "mastodon".
+type LinkRule struct {
+	// Platforms the rule applies to, all if empty
+	Platforms []string `json:"Platforms,omitempty"`
+	// Domains (including their subdomains) the rule applies to, all if empty
+	Domains []string `json:"Domains,omitempty"`
+	// Domains the rule doesn't apply to, e.g. the own ones for third-party links
+	ExcludeDomains []string `json:"ExcludeDomains,omitempty"`
+	// Upgrade http:// links to https://
+	HTTPS bool `json:"HTTPS,omitempty"`
+	// Remove tracking query parameters, e.g. utm_source and fbclid
+	StripTracking bool `json:"StripTracking,omitempty"`
+	// Query parameters to remove
+	Strip []string `json:"Strip,omitempty"`
+	// Query parameters to add, "{platform}" is replaced by the platform name
+	Query map[string]string `json:"Query,omitempty"`
+	// Rewrite the domain, e.g. to a mirror of the own blog
+	Domain string `json:"Domain,omitempty"`
+	// Rewrite the scheme, e.g. to "gemini" for the own Gemini capsule
+	Scheme string `json:"Scheme,omitempty"`
+}
+
+// Hashtags configures the hashtag management. Platform names are lower case,
+// e.g. "mastodon".
+type Hashtags struct {
+	// Appended per platform, e.g. {"mastodon": ["#gos"]}
+	Platforms map[string][]string `json:"Platforms,omitempty"`
+	// Appended per entry tag, e.g. {"evergreen": ["#Throwback"]}
+	Tags map[string][]string `json:"Tags,omitempty"`
+	// Maximum number of hashtags per platform, e.g. {"linkedin": 5}
+	Max map[string]int `json:"Max,omitempty"`
+	// Rewrite hashtags to CamelCase, so that screen readers read them word by word
+	CamelCase bool `json:"CamelCase,omitempty"`
+	// Preferred spellings for the CamelCase rewrite, e.g. ["#OpenSource"]. The
+	// spellings used by posted entries are learned automatically.
+	Spellings []string `json:"Spellings,omitempty"`
 }
 
 func New(configPath string, composeEntry bool) (Config, error) {
@@ -45,6 +102,11 @@ func New(configPath string, composeEntry bool) (Config, error) {
 		return conf, conf.WriteToDisk(configPath)
 	}
 
+	// The config file may contain secrets.
+	if err := CheckPermissions(configPath); err != nil {
+		return conf, err
+	}
+
 	file, err := os.Open(configPath)
 	if err != nil {
 		return conf, fmt.Errorf("failed to open file: %w", err)
@@ -65,21 +127,54 @@ func New(configPath string, composeEntry bool) (Config, error) {
 		return conf, fmt.Errorf("failed to unmarshal JSON: %w", err)
 	}
 
+	if err := conf.resolveSecrets(); err != nil {
+		return conf, err
+	}
+	creds, err := readCredentials(CredentialsPath(configPath))
+	if err != nil {
+		return conf, err
+	}
+	conf.merge(creds)
+
 	return conf, nil
 }
 
+// WriteToDisk writes the config file and the tokens gos wrote back into the
+// credentials file (or the encrypted credential store, if in use), all readable
+// by the owner only. Secret references are written back as references. A store
+// not unlocked yet is only unlocked if there are tokens to write.
 func (s Config) WriteToDisk(configPath string) error {
-	colour.Infoln("Writing", configPath)
-	if err := os.MkdirAll(filepath.Dir(configPath), os.ModePerm); err != nil {
+	if _, creds := s.split(); s.store == nil && !creds.empty() {
+		if err := s.UnlockStore(configPath); err != nil {
+			return err
+		}
+	}
+	if s.store != nil {
+		return s.writeToStore(configPath)
+	}
+	conf, creds := s.split()
+	if err := writeJSON(configPath, conf); err != nil {
+		return err
+	}
+	credsPath := CredentialsPath(configPath)
+	if _, err := os.Stat(credsPath); creds.empty() && os.IsNotExist(err) {
+		return nil
+	}
+	return writeJSON(credsPath, creds)
+}
+
+func writeJSON(path string, v any) error {
+	colour.Infoln("Writing", path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
 		return err
 	}
 
-	bytes, err := json.MarshalIndent(s, "", "  ")
+	bytes, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal JSON: %w", err)
 	}
-	tmpConfigPath := fmt.Sprintf("%s.tmp", configPath)
-	file, err := os.Create(tmpConfigPath)
+	tmpPath := fmt.Sprintf("%s.tmp", path)
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
@@ -90,11 +185,15 @@ func (s Config) WriteToDisk(configPath string) error {
 		}
 	}()
 
+	// Also tighten a tmp file left behind with other permissions.
+	if err := file.Chmod(0o600); err != nil {
+		return err
+	}
 	if _, err := file.Write(bytes); err != nil {
 		return fmt.Errorf("failed to write to file: %w", err)
 	}
 
-	return os.Rename(tmpConfigPath, configPath)
+	return os.Rename(tmpPath, path)
 }
 
 // IsPaused checks if the current time falls within the configured pause period
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"testing"
 	"time"
 )
@@ -190,3 +191,27 @@ func TestIsPausedCurrentTime(t *testing.T) {
 		t.Errorf("Expected not to be paused for past dates, but got true")
 	}
 }
+
+func TestParsePlatforms(t *testing.T) {
+	args := Args{Config: Config{LinkedInOrgs: map[string]string{"Acme": "42"}}}
+	if err := args.ParsePlatforms("Mastodon:500,LinkedIn:1000,LinkedInOrg:acme:900,Noop"); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"Mastodon": 500, "LinkedIn": 1000, "LinkedInOrg-acme": 900, "Noop": 500}
+	if !maps.Equal(args.Platforms, expected) {
+		t.Errorf("expected %v but got %v", expected, args.Platforms)
+	}
+
+	if err := args.ParsePlatforms("LinkedInOrg:other:900"); err == nil {
+		t.Error("expected an error for a LinkedIn organisation not in LinkedInOrgs")
+	}
+	if orgID, ok := args.Config.LinkedInOrg("ACME"); !ok || orgID != "42" {
+		t.Errorf("expected the case-insensitive organisation ID 42 but got %q", orgID)
+	}
+
+	for platform, network := range map[string]string{"Mastodon": "mastodon", "LinkedInOrg-acme": "linkedin"} {
+		if got := Network(platform); got != network {
+			t.Errorf("expected network %s for %s but got %s", network, platform, got)
+		}
+	}
+}
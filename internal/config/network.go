@@ -0,0 +1,32 @@
+package config
+
+import "strings"
+
+// LinkedInOrgPrefix prefixes the platform names of LinkedIn organisation pages,
+// e.g. linkedinorg-acme for the page configured as "acme" in LinkedInOrgs.
+const LinkedInOrgPrefix = "linkedinorg-"
+
+// Networks are the social networks gos knows, by their platform names.
+var Networks = []string{"linkedin", "mastodon", "noop", "xcom"}
+
+// Network returns the social network of the platform. That's the platform itself,
+// except for LinkedIn organisation pages, which are on linkedin.
+func Network(platform string) string {
+	platform = strings.ToLower(platform)
+	if strings.HasPrefix(platform, LinkedInOrgPrefix) {
+		return "linkedin"
+	}
+	return platform
+}
+
+// LinkedInOrg returns the ID of the LinkedIn organisation page configured as org
+// in LinkedInOrgs. The org is case-insensitive, like the platform names, so that
+// e.g. linkedinorg-acme finds the page configured as "Acme".
+func (c Config) LinkedInOrg(org string) (string, bool) {
+	for name, orgID := range c.LinkedInOrgs {
+		if strings.EqualFold(name, org) {
+			return orgID, orgID != ""
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"codeberg.org/snonux/gos/internal/colour"
+)
+
+// The name of the credentials file in the config directory.
+const credentialsFile = "credentials.json"
+
+// Credentials are the tokens gos writes back, e.g. after the OAuth2 authorisation.
+// They are stored in the credentials file next to the config file, readable by
+// the owner only, and override the values of the config file.
+type Credentials struct {
+	MastodonAccessToken        string `json:"MastodonAccessToken,omitempty"`
+	LinkedInAccessToken        string `json:"LinkedInAccessToken,omitempty"`
+	LinkedInPersonID           string `json:"LinkedInPersonID,omitempty"`
+	LinkedInRefreshToken       string `json:"LinkedInRefreshToken,omitempty"`
+	LinkedInTokenExpiry        int64  `json:"LinkedInTokenExpiry,omitempty"`
+	LinkedInRefreshTokenExpiry int64  `json:"LinkedInRefreshTokenExpiry,omitempty"`
+	// The values of the config file the tokens superseded, e.g. a secret reference,
+	// so that a value changed since takes precedence again.
+	Sources map[string]string `json:"Sources,omitempty"`
+}
+
+// A secret reference of the config file, e.g. "env:GOS_MASTODON_TOKEN", and the
+// value it was resolved to.
+type secretRef struct {
+	ref, value string
+}
+
+// The config fields which may be secret references.
+var secretFields = map[string]func(*Config) *string{
+	"MastodonAccessToken":  func(c *Config) *string { return &c.MastodonAccessToken },
+	"LinkedInClientID":     func(c *Config) *string { return &c.LinkedInClientID },
+	"LinkedInSecret":       func(c *Config) *string { return &c.LinkedInSecret },
+	"LinkedInAccessToken":  func(c *Config) *string { return &c.LinkedInAccessToken },
+	"LinkedInRefreshToken": func(c *Config) *string { return &c.LinkedInRefreshToken },
+}
+
+// CredentialsPath returns the path of the credentials file, which is in the
+// directory of the config file.
+func CredentialsPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), credentialsFile)
+}
+
+// ResolveSecret resolves a secret reference: "env:NAME" is the value of the
+// environment variable, "file:PATH" the content of the file and "cmd:COMMAND"
+// the output of the shell command, e.g. "cmd:pass show gos/mastodon". Leading
+// and trailing white space is trimmed. Any other value is returned as it is.
+func ResolveSecret(value string) (string, error) {
+	kind, arg, ok := strings.Cut(value, ":")
+	if !ok {
+		return value, nil
+	}
+	switch kind {
+	case "env":
+		secret, ok := os.LookupEnv(arg)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s not set", arg)
+		}
+		return strings.TrimSpace(secret), nil
+	case "file":
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	case "cmd":
+		var stderr bytes.Buffer
+		cmd := exec.Command("sh", "-c", arg)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("command %q failed: %w: %s", arg, err, strings.TrimSpace(stderr.String()))
+		}
+		return strings.TrimSpace(string(out)), nil
+	default:
+		return value, nil
+	}
+}
+
+// Resolves the secret references of the config, remembering them, so that
+// WriteToDisk writes the references and not the secrets back.
+func (c *Config) resolveSecrets() error {
+	c.refs = make(map[string]secretRef)
+	for name, field := range secretFields {
+		ref := *field(c)
+		value, err := ResolveSecret(ref)
+		if err != nil {
+			return fmt.Errorf("unable to resolve %s: %w", name, err)
+		}
+		if value != ref {
+			c.refs[name] = secretRef{ref: ref, value: value}
+			*field(c) = value
+		}
+	}
+	return nil
+}
+
+// Reports whether the field still holds the value its secret reference was
+// resolved to.
+func (c Config) unchanged(name string) bool {
+	ref, ok := c.refs[name]
+	return ok && *secretFields[name](&c) == ref.value
+}
+
+// Splits the config into the part written to the config file and the written-back
+// credentials. Fields still holding the value of their secret reference are
+// written as the reference.
+func (c Config) split() (Config, Credentials) {
+	creds := Credentials{
+		MastodonAccessToken:        c.MastodonAccessToken,
+		LinkedInAccessToken:        c.LinkedInAccessToken,
+		LinkedInPersonID:           c.LinkedInPersonID,
+		LinkedInRefreshToken:       c.LinkedInRefreshToken,
+		LinkedInTokenExpiry:        c.LinkedInTokenExpiry,
+		LinkedInRefreshTokenExpiry: c.LinkedInRefreshTokenExpiry,
+	}
+	conf := c
+	conf.MastodonAccessToken = ""
+	conf.LinkedInAccessToken = ""
+	conf.LinkedInPersonID = ""
+	conf.LinkedInRefreshToken = ""
+	conf.LinkedInTokenExpiry = 0
+	conf.LinkedInRefreshTokenExpiry = 0
+
+	credFields := map[string]*string{
+		"MastodonAccessToken":  &creds.MastodonAccessToken,
+		"LinkedInAccessToken":  &creds.LinkedInAccessToken,
+		"LinkedInRefreshToken": &creds.LinkedInRefreshToken,
+	}
+	for name, ref := range c.refs {
+		credField, isCred := credFields[name]
+		switch {
+		case isCred:
+			// The reference stays, a new token goes to the credentials.
+			*secretFields[name](&conf) = ref.ref
+			if c.unchanged(name) {
+				*credField = ""
+			}
+		case c.unchanged(name):
+			*secretFields[name](&conf) = ref.ref
+		}
+	}
+	for name, credField := range credFields {
+		if *credField == "" {
+			continue
+		}
+		if creds.Sources == nil {
+			creds.Sources = make(map[string]string)
+		}
+		creds.Sources[name] = *secretFields[name](&conf)
+	}
+	return conf, creds
+}
+
+// Returns the value of the field in the config file, i.e. its secret reference,
+// if any.
+func (c Config) source(name string) string {
+	if ref, ok := c.refs[name]; ok {
+		return ref.ref
+	}
+	return *secretFields[name](&c)
+}
+
+// Reports whether the written-back token overrides the value of the config file.
+// It doesn't, if the value was changed since the token was written, e.g. to a new
+// secret reference. Credentials of older gos versions don't tell, so the token
+// overrides it, but with a warning.
+func (c Config) overrides(name string, sources map[string]string) bool {
+	source := c.source(name)
+	if source == "" {
+		return true
+	}
+	superseded, ok := sources[name]
+	switch {
+	case !ok:
+		colour.Warnln(name, "is set in both the config file and", credentialsFile+", using the latter")
+		return true
+	case superseded != source:
+		colour.Infoln("Using", name, "of the config file, as it changed since the token in", credentialsFile, "was written")
+		return false
+	default:
+		return true
+	}
+}
+
+// Overrides the config with the written-back credentials (see overrides). The
+// expiry of a token not used is unknown.
+func (c *Config) merge(creds Credentials) {
+	overrides := map[string]string{
+		"MastodonAccessToken":  creds.MastodonAccessToken,
+		"LinkedInAccessToken":  creds.LinkedInAccessToken,
+		"LinkedInRefreshToken": creds.LinkedInRefreshToken,
+	}
+	used := make(map[string]bool)
+	for name, value := range overrides {
+		if value != "" && c.overrides(name, creds.Sources) {
+			*secretFields[name](c) = value
+			used[name] = true
+		}
+	}
+	if creds.LinkedInPersonID != "" {
+		c.LinkedInPersonID = creds.LinkedInPersonID
+	}
+	if creds.LinkedInTokenExpiry != 0 && used["LinkedInAccessToken"] {
+		c.LinkedInTokenExpiry = creds.LinkedInTokenExpiry
+	}
+	if creds.LinkedInRefreshTokenExpiry != 0 && used["LinkedInRefreshToken"] {
+		c.LinkedInRefreshTokenExpiry = creds.LinkedInRefreshTokenExpiry
+	}
+}
+
+// Reports whether there are no credentials to write.
+func (c Credentials) empty() bool {
+	return reflect.DeepEqual(c, Credentials{})
+}
+
+// Reads the credentials file. A missing credentials file is empty.
+func readCredentials(path string) (Credentials, error) {
+	var creds Credentials
+	if err := CheckPermissions(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return creds, nil
+		}
+		return creds, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return creds, err
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return creds, fmt.Errorf("invalid credentials file %s: %w", path, err)
+	}
+	return creds, nil
+}
+
+// CheckPermissions refuses files holding secrets, which are accessible by the
+// group or by others.
+func CheckPermissions(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return fmt.Errorf("%s is accessible by group or others (mode %04o), run: chmod 600 %s", path, perm, path)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOS_TEST_SECRET", "from-env")
+
+	tests := []struct {
+		value       string
+		expected    string
+		expectError bool
+	}{
+		{"plain-token", "plain-token", false},
+		{"", "", false},
+		{"https://example.org", "https://example.org", false},
+		{"env:GOS_TEST_SECRET", "from-env", false},
+		{"env:GOS_TEST_UNSET_SECRET", "", true},
+		{"file:" + secretFile, "from-file", false},
+		{"file:" + filepath.Join(dir, "missing"), "", true},
+		{"cmd:echo from-cmd", "from-cmd", false},
+		{"cmd:exit 1", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			secret, err := ResolveSecret(tt.value)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("expected error %v but got %v", tt.expectError, err)
+			}
+			if secret != tt.expected {
+				t.Errorf("expected %q but got %q", tt.expected, secret)
+			}
+		})
+	}
+}
+
+func TestNewPermissions(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "gos.json")
+	if err := os.WriteFile(configPath, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(configPath, false); err == nil || !strings.Contains(err.Error(), "chmod 600") {
+		t.Errorf("expected a permission error but got %v", err)
+	}
+	if err := os.Chmod(configPath, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(configPath, false); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWriteToDiskCredentials(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "gos.json")
+	t.Setenv("GOS_TEST_MASTODON_TOKEN", "mastodon-token")
+	t.Setenv("GOS_TEST_LINKEDIN_SECRET", "linkedin-secret")
+	content := `{
+  "MastodonAccessToken": "env:GOS_TEST_MASTODON_TOKEN",
+  "LinkedInSecret": "env:GOS_TEST_LINKEDIN_SECRET",
+  "LinkedInAccessToken": "legacy-plaintext-token"
+}`
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := New(configPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.MastodonAccessToken != "mastodon-token" || conf.LinkedInSecret != "linkedin-secret" {
+		t.Fatalf("secret references not resolved: %+v", conf)
+	}
+
+	// As after a LinkedIn token refresh.
+	conf.LinkedInAccessToken = "new-token"
+	conf.LinkedInTokenExpiry = 42
+	if err := conf.WriteToDisk(configPath); err != nil {
+		t.Fatal(err)
+	}
+
+	var written map[string]any
+	readJSON(t, configPath, &written)
+	if written["MastodonAccessToken"] != "env:GOS_TEST_MASTODON_TOKEN" || written["LinkedInSecret"] != "env:GOS_TEST_LINKEDIN_SECRET" {
+		t.Errorf("expected the secret references to be written back but got %v", written)
+	}
+	if _, ok := written["LinkedInAccessToken"]; ok {
+		t.Errorf("expected no access token in the config file but got %v", written)
+	}
+
+	var creds Credentials
+	readJSON(t, CredentialsPath(configPath), &creds)
+	expected := Credentials{
+		LinkedInAccessToken: "new-token",
+		LinkedInTokenExpiry: 42,
+		Sources:             map[string]string{"LinkedInAccessToken": ""},
+	}
+	if !reflect.DeepEqual(creds, expected) {
+		t.Errorf("expected credentials %+v but got %+v", expected, creds)
+	}
+
+	for _, path := range []string{configPath, CredentialsPath(configPath)} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("expected %s to have mode 0600 but got %04o", path, perm)
+		}
+	}
+
+	conf, err = New(configPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.LinkedInAccessToken != "new-token" || conf.LinkedInTokenExpiry != 42 || conf.MastodonAccessToken != "mastodon-token" {
+		t.Errorf("credentials not loaded: %+v", conf)
+	}
+}
+
+func TestMergeChangedReference(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "gos.json")
+	t.Setenv("GOS_TEST_OLD_TOKEN", "old-token")
+	t.Setenv("GOS_TEST_NEW_TOKEN", "new-token")
+	writeConfig := func(ref string) {
+		content := `{"LinkedInAccessToken": "` + ref + `"}`
+		if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeConfig("env:GOS_TEST_OLD_TOKEN")
+	conf, err := New(configPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// As after a LinkedIn token refresh.
+	conf.LinkedInAccessToken = "refreshed-token"
+	conf.LinkedInTokenExpiry = 42
+	if err := conf.WriteToDisk(configPath); err != nil {
+		t.Fatal(err)
+	}
+	if conf, err = New(configPath, false); err != nil {
+		t.Fatal(err)
+	}
+	if conf.LinkedInAccessToken != "refreshed-token" || conf.LinkedInTokenExpiry != 42 {
+		t.Errorf("expected the refreshed token but got %+v", conf)
+	}
+
+	// The reference changed since the token was refreshed.
+	writeConfig("env:GOS_TEST_NEW_TOKEN")
+	if conf, err = New(configPath, false); err != nil {
+		t.Fatal(err)
+	}
+	if conf.LinkedInAccessToken != "new-token" || conf.LinkedInTokenExpiry != 0 {
+		t.Errorf("expected the token of the changed reference but got %+v", conf)
+	}
+	if err := conf.WriteToDisk(configPath); err != nil {
+		t.Fatal(err)
+	}
+	var creds Credentials
+	readJSON(t, CredentialsPath(configPath), &creds)
+	if creds.LinkedInAccessToken != "" {
+		t.Errorf("expected the stale token to be dropped but got %+v", creds)
+	}
+}
+
+func readJSON(t *testing.T, path string, v any) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
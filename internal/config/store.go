@@ -0,0 +1,296 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// The name of the encrypted credential store in the config directory.
+const storeFile = "credentials.enc"
+
+const (
+	storeVersion = 1
+	keySize      = 32
+	saltSize     = 16
+	nonceSize    = 24
+	// The scrypt parameters recommended for interactive logins.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// StoreSecrets are the names of the secrets the encrypted credential store holds.
+var StoreSecrets = []string{
+	"MastodonAccessToken",
+	"LinkedInClientID",
+	"LinkedInSecret",
+	"LinkedInAccessToken",
+	"LinkedInPersonID",
+	"LinkedInRefreshToken",
+	"LinkedInTokenExpiry",
+	"LinkedInRefreshTokenExpiry",
+}
+
+// Passphrase returns the passphrase (or key) unlocking the encrypted credential
+// store. It is asked for twice when confirm is set, e.g. for a new store. The main
+// function sets it, e.g. to prompt for the passphrase.
+var Passphrase = func(confirm bool) ([]byte, error) {
+	return nil, errors.New("no passphrase for the encrypted credential store")
+}
+
+// The encrypted credential store as written to disk.
+type storeData struct {
+	Version int
+	Salt    []byte
+	N, R, P int
+	Nonce   []byte
+	Box     []byte
+}
+
+// Store is the encrypted credential store, a NaCl secretbox keyed by the scrypt
+// hash of a passphrase, which maps the secret names to their values.
+type Store struct {
+	path    string
+	salt    []byte
+	key     [keySize]byte
+	secrets map[string]string
+}
+
+// StorePath returns the path of the encrypted credential store, which is in the
+// directory of the config file.
+func StorePath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), storeFile)
+}
+
+// NewStore returns a new, empty store, which is written by Save.
+func NewStore(path string, passphrase []byte) (*Store, error) {
+	store := &Store{path: path, secrets: make(map[string]string)}
+	if err := store.Rekey(passphrase); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// OpenStore decrypts the store with the passphrase.
+func OpenStore(path string, passphrase []byte) (*Store, error) {
+	if err := CheckPermissions(path); err != nil {
+		return nil, err
+	}
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var data storeData
+	if err := json.Unmarshal(bytes, &data); err != nil {
+		return nil, fmt.Errorf("invalid credential store %s: %w", path, err)
+	}
+	if data.Version != storeVersion || len(data.Nonce) != nonceSize {
+		return nil, fmt.Errorf("unsupported credential store %s", path)
+	}
+	key, err := scrypt.Key(passphrase, data.Salt, data.N, data.R, data.P, keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &Store{path: path, salt: data.Salt}
+	copy(store.key[:], key)
+	var nonce [nonceSize]byte
+	copy(nonce[:], data.Nonce)
+	plain, ok := secretbox.Open(nil, data.Box, &nonce, &store.key)
+	if !ok {
+		return nil, fmt.Errorf("unable to decrypt %s: wrong passphrase or corrupted store", path)
+	}
+	if err := json.Unmarshal(plain, &store.secrets); err != nil {
+		return nil, fmt.Errorf("invalid credential store %s: %w", path, err)
+	}
+	if store.secrets == nil {
+		store.secrets = make(map[string]string)
+	}
+	return store, nil
+}
+
+// Get returns the secret with the name.
+func (s *Store) Get(name string) (string, bool) {
+	value, ok := s.secrets[name]
+	return value, ok
+}
+
+// Set sets the secret with the name, an empty value deletes it.
+func (s *Store) Set(name, value string) {
+	if value == "" {
+		delete(s.secrets, name)
+		return
+	}
+	s.secrets[name] = value
+}
+
+// Names returns the sorted names of the secrets in the store.
+func (s *Store) Names() []string {
+	names := make([]string, 0, len(s.secrets))
+	for name := range s.secrets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Rekey derives a new key from the passphrase, with a new salt. The secrets are
+// encrypted with it by the next Save.
+func (s *Store) Rekey(passphrase []byte) error {
+	if len(passphrase) == 0 {
+		return errors.New("empty passphrase")
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return err
+	}
+	s.salt = salt
+	copy(s.key[:], key)
+	return nil
+}
+
+// Save encrypts the secrets with a new nonce and writes the store.
+func (s *Store) Save() error {
+	plain, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+	var nonce [nonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+	return writeJSON(s.path, storeData{
+		Version: storeVersion,
+		Salt:    s.salt,
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Nonce:   nonce[:],
+		Box:     secretbox.Seal(nil, plain, &nonce, &s.key),
+	})
+}
+
+// Store returns the encrypted credential store in use, nil if there is none.
+func (c Config) Store() *Store {
+	return c.store
+}
+
+// EnableStore creates the encrypted credential store, asking for a new
+// passphrase, and moves the secrets of the config file and the tokens of the
+// credentials file into it. An existing store is unlocked instead.
+func (c *Config) EnableStore(configPath string) error {
+	if err := c.UnlockStore(configPath); err != nil || c.store != nil {
+		return err
+	}
+	passphrase, err := Passphrase(true)
+	if err != nil {
+		return err
+	}
+	if c.store, err = NewStore(StorePath(configPath), passphrase); err != nil {
+		return err
+	}
+	return c.WriteToDisk(configPath)
+}
+
+// UnlockStore opens the encrypted credential store, if there is one, and overrides
+// the config with its secrets. As it may prompt for the passphrase, it is only
+// called for the commands and platforms which need the secrets, and not by New.
+func (c *Config) UnlockStore(configPath string) error {
+	if c.store != nil {
+		return nil
+	}
+	path := StorePath(configPath)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	passphrase, err := Passphrase(false)
+	if err != nil {
+		return err
+	}
+	if c.store, err = OpenStore(path, passphrase); err != nil {
+		return err
+	}
+
+	for name, field := range secretFields {
+		if value, ok := c.store.Get(name); ok {
+			*field(c) = value
+		}
+	}
+	if value, ok := c.store.Get("LinkedInPersonID"); ok {
+		c.LinkedInPersonID = value
+	}
+	for name, field := range map[string]*int64{
+		"LinkedInTokenExpiry":        &c.LinkedInTokenExpiry,
+		"LinkedInRefreshTokenExpiry": &c.LinkedInRefreshTokenExpiry,
+	} {
+		value, ok := c.store.Get(name)
+		if !ok {
+			continue
+		}
+		if *field, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("invalid %s in %s: %w", name, path, err)
+		}
+	}
+	return nil
+}
+
+// Writes the config file without any plain text secrets, and the credentials and
+// the secrets into the store. Secret references stay in the config file, unless
+// overridden by the store.
+func (s Config) writeToStore(configPath string) error {
+	conf, creds := s.split()
+	for name, value := range map[string]string{
+		"MastodonAccessToken":  creds.MastodonAccessToken,
+		"LinkedInAccessToken":  creds.LinkedInAccessToken,
+		"LinkedInPersonID":     creds.LinkedInPersonID,
+		"LinkedInRefreshToken": creds.LinkedInRefreshToken,
+	} {
+		s.store.Set(name, value)
+	}
+	for name, value := range map[string]int64{
+		"LinkedInTokenExpiry":        creds.LinkedInTokenExpiry,
+		"LinkedInRefreshTokenExpiry": creds.LinkedInRefreshTokenExpiry,
+	} {
+		if value == 0 {
+			s.store.Set(name, "")
+			continue
+		}
+		s.store.Set(name, strconv.FormatInt(value, 10))
+	}
+	for _, name := range []string{"LinkedInClientID", "LinkedInSecret"} {
+		ref, isRef := s.refs[name]
+		switch {
+		case !isRef:
+			s.store.Set(name, *secretFields[name](&s))
+			*secretFields[name](&conf) = ""
+		case !s.unchanged(name):
+			s.store.Set(name, *secretFields[name](&s))
+			*secretFields[name](&conf) = ref.ref
+		}
+	}
+
+	if err := s.store.Save(); err != nil {
+		return err
+	}
+	if err := writeJSON(configPath, conf); err != nil {
+		return err
+	}
+	// The tokens of the credentials file are in the store now.
+	if err := os.Remove(CredentialsPath(configPath)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), storeFile)
+	store, err := NewStore(path, []byte("old passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("MastodonAccessToken", "mastodon-token")
+	store.Set("LinkedInSecret", "linkedin-secret")
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "mastodon-token") {
+		t.Errorf("expected the store to be encrypted but got %s", data)
+	}
+	if _, err := OpenStore(path, []byte("wrong passphrase")); err == nil {
+		t.Error("expected an error for the wrong passphrase")
+	}
+
+	store, err = OpenStore(path, []byte("old passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := store.Get("MastodonAccessToken"); value != "mastodon-token" {
+		t.Errorf("expected mastodon-token but got %q", value)
+	}
+
+	store.Set("LinkedInSecret", "")
+	if err := store.Rekey([]byte("new passphrase")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenStore(path, []byte("old passphrase")); err == nil {
+		t.Error("expected an error for the old passphrase")
+	}
+	store, err = OpenStore(path, []byte("new passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := store.Names(); !slices.Equal(names, []string{"MastodonAccessToken"}) {
+		t.Errorf("expected only MastodonAccessToken but got %v", names)
+	}
+}
+
+func TestEnableStore(t *testing.T) {
+	passphrase := Passphrase
+	t.Cleanup(func() { Passphrase = passphrase })
+	Passphrase = func(bool) ([]byte, error) { return []byte("passphrase"), nil }
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "gos.json")
+	t.Setenv("GOS_TEST_LINKEDIN_SECRET", "linkedin-secret")
+	content := `{
+  "MastodonAccessToken": "mastodon-token",
+  "LinkedInClientID": "client-id",
+  "LinkedInSecret": "env:GOS_TEST_LINKEDIN_SECRET"
+}`
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(CredentialsPath(configPath), []byte(`{"LinkedInAccessToken": "access-token", "LinkedInTokenExpiry": 42}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := New(configPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.EnableStore(configPath); err != nil {
+		t.Fatal(err)
+	}
+
+	var written map[string]any
+	readJSON(t, configPath, &written)
+	expected := map[string]any{
+		"MastodonAccessToken": "",
+		"LinkedInClientID":    "",
+		"LinkedInSecret":      "env:GOS_TEST_LINKEDIN_SECRET",
+		"LinkedInAccessToken": nil,
+		"LinkedInTokenExpiry": nil,
+	}
+	for key, value := range expected {
+		if written[key] != value {
+			t.Errorf("expected %s to be %v in the config file but got %v", key, value, written[key])
+		}
+	}
+	if _, err := os.Stat(CredentialsPath(configPath)); !os.IsNotExist(err) {
+		t.Errorf("expected the credentials file to be removed but got %v", err)
+	}
+
+	// The store is only unlocked once the secrets are needed.
+	Passphrase = func(bool) ([]byte, error) {
+		t.Fatal("expected no passphrase prompt while loading the config")
+		return nil, nil
+	}
+	conf, err = New(configPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Store() != nil || conf.MastodonAccessToken != "" {
+		t.Fatalf("expected the store to be locked but got %+v", conf)
+	}
+	conf.LastRunEpoch = 42
+	if err := conf.WriteToDisk(configPath); err != nil {
+		t.Fatal(err)
+	}
+
+	Passphrase = func(bool) ([]byte, error) { return []byte("passphrase"), nil }
+	if err := conf.UnlockStore(configPath); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Store() == nil {
+		t.Fatal("expected the store to be in use")
+	}
+	if conf.MastodonAccessToken != "mastodon-token" || conf.LinkedInClientID != "client-id" ||
+		conf.LinkedInSecret != "linkedin-secret" || conf.LinkedInAccessToken != "access-token" ||
+		conf.LinkedInTokenExpiry != 42 {
+		t.Errorf("secrets not loaded from the store: %+v", conf)
+	}
+	if _, ok := conf.Store().Get("LinkedInSecret"); ok {
+		t.Error("expected the secret reference to stay in the config file")
+	}
+}
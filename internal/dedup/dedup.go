@@ -0,0 +1,235 @@
+// Package dedup detects duplicate and near-duplicate entries, e.g. the same
+// article link queued twice or a reworded old post.
+package dedup
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/entry"
+	"codeberg.org/snonux/gos/internal/link"
+)
+
+const (
+	// Number of words per shingle.
+	shingleSize = 3
+	// DefaultThreshold is the similarity from which on entries are near duplicates.
+	DefaultThreshold = 0.6
+	// DefaultLinkThreshold is the similarity from which on entries sharing a link
+	// are duplicates. It is lower, but not zero, as e.g. a series of posts about
+	// one article shares its link but not its text.
+	DefaultLinkThreshold = 0.3
+)
+
+var (
+	urlRE  = regexp.MustCompile(`(?:https?|ftp)://\S+`)
+	wordRE = regexp.MustCompile(`[\p{L}\p{N}#@]+`)
+)
+
+// Fingerprint is the normalised text (as word shingles) plus the canonicalised
+// URLs of an entry.
+type Fingerprint struct {
+	Path     string
+	Time     time.Time // When queued or posted
+	State    entry.State
+	shingles map[string]struct{}
+	urls     []string
+}
+
+// Match is an entry matching another one.
+type Match struct {
+	Fingerprint
+	Similarity float64 // Jaccard similarity of the shingles, 1 means the same text
+	SameURLs   []string
+}
+
+func (m Match) String() string {
+	what := fmt.Sprintf("%.0f%% similar", m.Similarity*100)
+	if len(m.SameURLs) > 0 {
+		what += fmt.Sprintf(", same links %v", m.SameURLs)
+	}
+	if m.Time.IsZero() {
+		return fmt.Sprintf("%s %s (%s)", m.State, m.Path, what)
+	}
+	return fmt.Sprintf("%s %s %s (%s)", m.State, m.Time.Format(time.DateTime), m.Path, what)
+}
+
+// New returns the fingerprint of the entry.
+func New(en entry.Entry) (Fingerprint, error) {
+	content, urls, err := en.Content()
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	fp := Fingerprint{Path: en.Path, Time: en.Time, State: en.State, shingles: shingles(content)}
+	for _, u := range urls {
+		if canonical := CanonicalURL(u); !slices.Contains(fp.urls, canonical) {
+			fp.urls = append(fp.urls, canonical)
+		}
+	}
+	return fp, nil
+}
+
+// Compare returns how similar the other fingerprint is.
+func (fp Fingerprint) Compare(other Fingerprint) Match {
+	m := Match{Fingerprint: other, Similarity: jaccard(fp.shingles, other.shingles)}
+	for _, u := range fp.urls {
+		if slices.Contains(other.urls, u) {
+			m.SameURLs = append(m.SameURLs, u)
+		}
+	}
+	return m
+}
+
+// Duplicate returns true if the text similarity of the match is at least the
+// threshold, or at least the link threshold if it shares a link. A threshold of
+// zero or less means the default one.
+func (m Match) Duplicate(threshold, linkThreshold float64) bool {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	if linkThreshold <= 0 {
+		linkThreshold = DefaultLinkThreshold
+	}
+	return m.Similarity >= threshold || (len(m.SameURLs) > 0 && m.Similarity >= linkThreshold)
+}
+
+// SameEntry returns true if both are copies of the same entry, e.g. in ./db and in
+// the ./db/platforms/PLATFORM directories. Different entries of the same name,
+// e.g. a new entry named like an old posted one, are not the same.
+func SameEntry(a, b entry.Entry) bool {
+	if a.Path == b.Path {
+		return true
+	}
+	return !a.Time.IsZero() && a.Time.Equal(b.Time) && a.Name() == b.Name()
+}
+
+// Index holds the fingerprints of all entries queued or posted within a window,
+// so that several entries can be checked while reading the others only once.
+type Index struct {
+	entries []entry.Entry
+	fps     []Fingerprint
+}
+
+// NewIndex indexes all queued and posted entries of the gosDir, which were queued
+// or posted within the window (a window of zero or less means forever).
+func NewIndex(gosDir string, window time.Duration) (*Index, error) {
+	var paths []string
+	for _, pattern := range []string{"db/*.queued", "db/platforms/*/*.queued", "db/platforms/*/*.posted"} {
+		matches, err := filepath.Glob(filepath.Join(gosDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+
+	ix := &Index{}
+	since := time.Now().Add(-window)
+	for _, path := range paths {
+		en, err := entry.New(path)
+		if err != nil {
+			colour.Infoln(err)
+			continue
+		}
+		if window > 0 && en.Time.Before(since) {
+			continue
+		}
+		fp, err := New(en)
+		if err != nil {
+			return nil, err
+		}
+		ix.entries = append(ix.entries, en)
+		ix.fps = append(ix.fps, fp)
+	}
+	return ix, nil
+}
+
+// Find returns all indexed entries which are duplicates of the entry. Copies of
+// the entry itself are ignored.
+func (ix *Index) Find(en entry.Entry, threshold, linkThreshold float64) ([]Match, error) {
+	fp, err := New(en)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		results []Match
+		seen    = make(map[string]struct{})
+	)
+	for i, other := range ix.entries {
+		if SameEntry(en, other) {
+			continue
+		}
+		// Only report each entry once, not every platform copy of it.
+		key := fmt.Sprintf("%s.%s.%s", other.Name(), other.Time.Format(time.DateTime), other.State)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		if m := fp.Compare(ix.fps[i]); m.Duplicate(threshold, linkThreshold) {
+			seen[key] = struct{}{}
+			results = append(results, m)
+		}
+	}
+	slices.SortFunc(results, func(a, b Match) int {
+		return b.Time.Compare(a.Time)
+	})
+	return results, nil
+}
+
+// CanonicalURL canonicalises the URL, so that e.g. http://www.foo.zone/bar/?utm_source=x
+// and https://foo.zone/bar are the same.
+func CanonicalURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimRight(rawURL, ".,;:!?)"))
+	if err != nil {
+		return rawURL
+	}
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	u.Host = strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	u.Fragment = ""
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	query := u.Query()
+	for key := range query {
+		if link.IsTracking(key) {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// shingles returns the set of word shingles of the text, without URLs and
+// punctuation, and in lower case.
+func shingles(text string) map[string]struct{} {
+	words := wordRE.FindAllString(strings.ToLower(urlRE.ReplaceAllString(text, " ")), -1)
+	set := make(map[string]struct{})
+	if len(words) < shingleSize {
+		if len(words) > 0 {
+			set[strings.Join(words, " ")] = struct{}{}
+		}
+		return set
+	}
+	for i := 0; i+shingleSize <= len(words); i++ {
+		set[strings.Join(words[i:i+shingleSize], " ")] = struct{}{}
+	}
+	return set
+}
+
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	var intersection int
+	for shingle := range a {
+		if _, ok := b[shingle]; ok {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
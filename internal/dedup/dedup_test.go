@@ -0,0 +1,123 @@
+package dedup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/entry"
+	"codeberg.org/snonux/gos/internal/timestamp"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	table := map[string]string{
+		"https://foo.zone/bar":                           "https://foo.zone/bar",
+		"http://www.Foo.zone/bar/":                       "https://foo.zone/bar",
+		"https://foo.zone/bar?utm_source=x&utm_medium=y": "https://foo.zone/bar",
+		"https://foo.zone/bar?id=42&fbclid=abc#section":  "https://foo.zone/bar?id=42",
+		"https://foo.zone/bar.":                          "https://foo.zone/bar",
+	}
+	for input, expected := range table {
+		if got := CanonicalURL(input); got != expected {
+			t.Errorf("expected %s for %s but got %s", expected, input, got)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	gosDir := t.TempDir()
+	recent := timestamp.Now()
+	old := time.Now().Add(-400 * 24 * time.Hour).Format(timestamp.Format)
+	files := map[string]string{
+		"new.txt": "Just released a new version of gos, the social media scheduler! #gos https://codeberg.org/snonux/gos",
+		"db/platforms/mastodon/reworded.txt." + recent + ".posted": "Just released a new version of gos, the social media scheduler written in Go! #gos",
+		"db/platforms/mastodon/samelink.txt." + recent + ".queued": "Just released a new version of gos, check it out #foo http://www.codeberg.org/snonux/gos/?utm_source=x",
+		"db/platforms/linkedin/samelink.txt." + recent + ".queued": "Just released a new version of gos, check it out #foo http://www.codeberg.org/snonux/gos/?utm_source=x",
+		"db/platforms/mastodon/linkonly.txt." + recent + ".queued": "Something completely different #bar https://codeberg.org/snonux/gos",
+		"db/platforms/mastodon/other.txt." + recent + ".posted":    "Something completely different #bar",
+		"db/platforms/mastodon/tooold.txt." + old + ".posted":      "Just released a new version of gos, the social media scheduler! #gos",
+		// An old entry of the same name is a different entry.
+		"db/platforms/mastodon/new.txt." + recent + ".posted": "Just released a new version of gos, the social media scheduler! #gos",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(gosDir, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	en, err := entry.New(filepath.Join(gosDir, "new.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := NewIndex(gosDir, 365*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, err := index.Find(en, DefaultThreshold, DefaultLinkThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 3 {
+		t.Errorf("expected 3 matches but got %v", matches)
+	}
+	for _, m := range matches {
+		switch filepath.Base(m.Path) {
+		case "reworded.txt." + recent + ".posted":
+			if m.Similarity < DefaultThreshold || len(m.SameURLs) > 0 {
+				t.Errorf("expected a near duplicate without same links but got %v", m)
+			}
+		case "samelink.txt." + recent + ".queued":
+			if m.Similarity >= DefaultThreshold || len(m.SameURLs) != 1 {
+				t.Errorf("expected a less similar text with the same link but got %v", m)
+			}
+		case "new.txt." + recent + ".posted":
+			if m.Similarity != 1 {
+				t.Errorf("expected the same text but got %v", m)
+			}
+		default:
+			t.Errorf("unexpected match %v", m)
+		}
+	}
+
+	// Without a window, the old entry is a duplicate too.
+	if index, err = NewIndex(gosDir, 0); err != nil {
+		t.Fatal(err)
+	}
+	if matches, err = index.Find(en, DefaultThreshold, DefaultLinkThreshold); err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 4 {
+		t.Errorf("expected 4 matches but got %v", matches)
+	}
+}
+
+func TestSameEntry(t *testing.T) {
+	table := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"gosdir/foo.txt", "gosdir/foo.txt", true},
+		{"gosdir/db/platforms/mastodon/foo.txt.20250101-010101.queued", "gosdir/db/platforms/linkedin/foo.txt.20250101-010101.queued", true},
+		{"gosdir/db/platforms/mastodon/foo.txt.20250101-010101.queued", "gosdir/db/platforms/mastodon/foo.txt.20250101-010101.posted", true},
+		{"gosdir/foo.txt", "gosdir/db/platforms/mastodon/foo.txt.20250101-010101.posted", false},
+		{"gosdir/db/platforms/mastodon/foo.txt.20250101-010101.posted", "gosdir/db/platforms/mastodon/foo.txt.20250102-010101.posted", false},
+	}
+	for _, tt := range table {
+		a, err := entry.New(tt.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := entry.New(tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if same := SameEntry(a, b); same != tt.expected {
+			t.Errorf("expected %v for %s and %s but got %v", tt.expected, tt.a, tt.b, same)
+		}
+	}
+}
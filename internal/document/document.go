@@ -0,0 +1,166 @@
+// Package document checks the documents (PDFs) attached to entries, e.g. slide
+// decks, which are posted as LinkedIn document posts.
+package document
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/colour"
+)
+
+// The limits of the LinkedIn documents API.
+const (
+	MaxSize  = 100 << 20
+	MaxPages = 300
+)
+
+const (
+	pdfHeader = "%PDF-"
+	// The PDF is read in chunks of this size. They overlap, so that nothing
+	// matched is split between two chunks.
+	chunkSize    = 1 << 20
+	chunkOverlap = 4 << 10
+)
+
+var (
+	errNotPDF = errors.New("not a PDF document")
+
+	// The page tree nodes, of which the root one counts all pages.
+	pagesRE = regexp.MustCompile(`<<[^<>]*/Type\s*/Pages\b[^<>]*>>`)
+	countRE = regexp.MustCompile(`/Count\s+(\d+)`)
+	// The pages themselves, in case the page tree nodes can't be found.
+	pageRE = regexp.MustCompile(`/Type\s*/Page\b`)
+)
+
+// Resolve returns the path of the document, local paths are relative to the gosDir.
+func Resolve(gosDir, filePath string) string {
+	if filepath.IsAbs(filePath) {
+		return filePath
+	}
+	return filepath.Join(gosDir, filePath)
+}
+
+// Title returns the default title of the document, its file name without the
+// extension.
+func Title(filePath string) string {
+	base := filepath.Base(filePath)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// Check checks whether the document exists and is within the size and page
+// limits of LinkedIn. It returns the number of pages, which is false if they
+// can't be counted (see Pages). The result is remembered until the document
+// changes, as it is checked when linting, queueing and posting.
+func Check(filePath string) (int, bool, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return 0, false, err
+	}
+	if info.Size() > MaxSize {
+		return 0, false, fmt.Errorf("%s is too large (%d MB > %d MB)", filePath, info.Size()>>20, MaxSize>>20)
+	}
+
+	checkedMu.Lock()
+	defer checkedMu.Unlock()
+	if c, ok := checked[filePath]; ok && c.size == info.Size() && c.modTime.Equal(info.ModTime()) {
+		return c.pages, c.ok, c.err
+	}
+	c := check{size: info.Size(), modTime: info.ModTime()}
+	c.pages, c.ok, c.err = countPages(filePath)
+	checked[filePath] = c
+	return c.pages, c.ok, c.err
+}
+
+// The result of a check of a document.
+type check struct {
+	size    int64
+	modTime time.Time
+	pages   int
+	ok      bool
+	err     error
+}
+
+var (
+	checkedMu sync.Mutex
+	checked   = make(map[string]check)
+)
+
+func countPages(filePath string) (int, bool, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			colour.Errorln("Error closing", filePath, ":", err)
+		}
+	}()
+	pages, ok, err := Pages(file)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", filePath, err)
+	}
+	if ok && pages > MaxPages {
+		return pages, ok, fmt.Errorf("%s has got too many pages (%d > %d)", filePath, pages, MaxPages)
+	}
+	return pages, ok, nil
+}
+
+// Pages returns the number of pages of the PDF. It is false, if the pages can't
+// be counted without decompressing the PDF, e.g. with compressed object streams.
+// The PDF is read in chunks, so that large documents aren't read into memory.
+func Pages(r io.Reader) (int, bool, error) {
+	header := make([]byte, len(pdfHeader))
+	if _, err := io.ReadFull(r, header); err != nil || string(header) != pdfHeader {
+		return 0, false, errNotPDF
+	}
+	var (
+		count, pages int
+		buf          = make([]byte, 0, chunkSize+chunkOverlap)
+	)
+	for {
+		n, err := io.ReadFull(r, buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		last := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !last {
+			return 0, false, err
+		}
+		// Pages starting in the overlap are counted with the next chunk, which
+		// contains them completely.
+		limit := len(buf)
+		if !last {
+			limit -= chunkOverlap
+		}
+		for _, node := range pagesRE.FindAll(buf, -1) {
+			if m := countRE.FindSubmatch(node); m != nil {
+				if c, err := strconv.Atoi(string(m[1])); err == nil && c > count {
+					count = c
+				}
+			}
+		}
+		for _, loc := range pageRE.FindAllIndex(buf, -1) {
+			if loc[0] < limit {
+				pages++
+			}
+		}
+		if last {
+			break
+		}
+		buf = buf[:copy(buf, buf[limit:])]
+	}
+	if count > 0 {
+		return count, true, nil
+	}
+	if pages > 0 {
+		return pages, true, nil
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,90 @@
+package document
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Returns a minimal PDF with the page tree and the pages.
+func pdf(pages int) string {
+	var sb strings.Builder
+	sb.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	fmt.Fprintf(&sb, "2 0 obj\n<< /Kids [3 0 R] /Type /Pages /Count %d >>\nendobj\n", pages)
+	for range pages {
+		sb.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R >>\nendobj\n")
+	}
+	sb.WriteString("%%EOF\n")
+	return sb.String()
+}
+
+func TestPages(t *testing.T) {
+	table := map[string]struct {
+		pages int
+		ok    bool
+	}{
+		pdf(3): {3, true},
+		"%PDF-1.4\n<< /Type /Page >>\n<< /Type/Page >>\n<< /Type /Pages >>\n": {2, true},
+		"%PDF-1.5\n<< /Type /ObjStm /N 5 >>\nstream\n...\nendstream\n":        {0, false},
+	}
+	for data, expected := range table {
+		pages, ok, err := Pages(strings.NewReader(data))
+		if err != nil {
+			t.Error(err)
+		}
+		if pages != expected.pages || ok != expected.ok {
+			t.Errorf("expected %d pages (%v) but got %d (%v) for %q", expected.pages, expected.ok, pages, ok, data)
+		}
+	}
+	if _, _, err := Pages(strings.NewReader("<html></html>")); err == nil {
+		t.Error("expected an error for a non PDF document")
+	}
+}
+
+func TestPagesChunked(t *testing.T) {
+	// Enough pages to span several chunks, without the page tree node.
+	var sb strings.Builder
+	sb.WriteString("%PDF-1.4\n")
+	for range 100000 {
+		sb.WriteString("<< /Type /Page /Parent 2 0 R >>\n")
+	}
+	if sb.Len() < 2*chunkSize {
+		t.Fatalf("expected at least two chunks but got %d bytes", sb.Len())
+	}
+	pages, ok, err := Pages(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || pages != 100000 {
+		t.Errorf("expected 100000 pages but got %d (%v)", pages, ok)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	table := map[string]struct {
+		content string
+		valid   bool
+	}{
+		"deck.pdf":    {pdf(10), true},
+		"huge.pdf":    {pdf(MaxPages + 1), false},
+		"invalid.pdf": {"not a pdf", false},
+	}
+	for name, tc := range table {
+		filePath := filepath.Join(dir, name)
+		if err := os.WriteFile(filePath, []byte(tc.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := Check(filePath); (err == nil) != tc.valid {
+			t.Errorf("expected %s to be valid=%v but got %v", name, tc.valid, err)
+		}
+	}
+	if _, _, err := Check(filepath.Join(dir, "missing.pdf")); err == nil {
+		t.Error("expected an error for a missing document")
+	}
+	if title := Title("./slides/my-talk.pdf"); title != "my-talk" {
+		t.Errorf("expected title 'my-talk' but got '%s'", title)
+	}
+}
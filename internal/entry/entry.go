@@ -5,15 +5,21 @@ package entry
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/journal"
+	"codeberg.org/snonux/gos/internal/markdown"
 	"codeberg.org/snonux/gos/internal/oi"
 	"codeberg.org/snonux/gos/internal/prompt"
+	"codeberg.org/snonux/gos/internal/textlen"
 	"codeberg.org/snonux/gos/internal/timestamp"
 )
 
@@ -32,7 +38,15 @@ const (
 )
 
 // validTags contains the list of valid tags that can be applied to entries.
-var validTags = []string{"ask", "prio", "now"}
+var validTags = []string{"ask", "prio", "soon", "now", "evergreen"}
+
+// The series name and part number, e.g. series:golang:2
+const seriesTagPrefix = "series:"
+
+// PrioTags are the tags controlling the order in which queued entries are selected.
+var PrioTags = []string{"now", "prio", "soon"}
+
+var hashtagRE = regexp.MustCompile(`#\w+`)
 
 // ErrSizeLimitExceeded is returned when an entry exceeds the size limit for a platform.
 var ErrSizeLimitExceeded = errors.New("message size limit exceeded")
@@ -57,10 +71,11 @@ var Zero = Entry{}
 var Zeroes = []Entry{}
 
 type Entry struct {
-	Path  string
-	Time  time.Time
-	State State
-	Tags  map[string]struct{}
+	Path        string
+	Time        time.Time
+	State       State
+	Tags        map[string]struct{} // Tags of the file name merged with the front matter ones
+	FrontMatter FrontMatter
 }
 
 func (en Entry) String() string {
@@ -83,6 +98,9 @@ func New(filePath string) (Entry, error) {
 		return en, fmt.Errorf("not a valid entry path: %s", filePath)
 	}
 	en.extractTags(parts)
+	if err := en.readFrontMatter(); err != nil {
+		return en, err
+	}
 
 	switch parts[len(parts)-1] {
 	case "queued":
@@ -111,9 +129,83 @@ func New(filePath string) (Entry, error) {
 	return en, nil
 }
 
+// Returns the default content, without any front matter and platform variants,
+// and its URLs. Markdown entries are rendered into plain text.
 func (en *Entry) Content() (string, []string, error) {
+	return en.ContentFor(defaultVariant, nil)
+}
+
+// ContentFor returns the content variant for the platform and its URLs. The
+// variant is either a "--- PLATFORM ---" section of the content, or the content
+// of the platform in the front matter. Otherwise, the default content is used.
+// The filters (see config.Args) are applied in order, e.g. to append hashtags.
+func (en Entry) ContentFor(platform string, filters []config.ContentFilter) (string, []string, error) {
+	variants, err := en.variants()
+	if err != nil {
+		return "", nil, err
+	}
+	content := variant(variants, platform)
+	if en.IsMarkdown() {
+		content = markdown.Render(platform, content)
+	}
+	if platform != defaultVariant {
+		tags := slices.Sorted(maps.Keys(en.Tags))
+		for _, filter := range filters {
+			content = filter(tags, platform, content)
+		}
+	}
+	return content, extractURLs(content), nil
+}
+
+func (en Entry) variants() (map[string]string, error) {
+	content, err := oi.SlurpAndTrim(en.Path)
+	if err != nil {
+		return nil, err
+	}
+	_, _, content = markdown.SplitFrontMatter(content)
+	variants := splitVariants(content)
+	for platform, override := range en.FrontMatter.Platforms {
+		if override.Content != "" {
+			variants[strings.ToLower(platform)] = strings.TrimSpace(override.Content)
+		}
+	}
+	return variants, nil
+}
+
+// Images returns the images referenced by the entry, either in the Markdown content
+// or in the alt texts of the front matter. Local paths are relative to the gosDir.
+// The alt texts of the front matter take precedence over the Markdown ones.
+func (en Entry) Images() ([]markdown.Image, error) {
 	content, err := oi.SlurpAndTrim(en.Path)
-	return content, extractURLs(content), err
+	if err != nil {
+		return nil, err
+	}
+	var images []markdown.Image
+	if en.IsMarkdown() {
+		_, _, body := markdown.SplitFrontMatter(content)
+		for _, image := range markdown.Images(body) {
+			if !slices.ContainsFunc(images, func(other markdown.Image) bool { return other.Path == image.Path }) {
+				images = append(images, image)
+			}
+		}
+	}
+	for i, image := range images {
+		if alt, ok := en.FrontMatter.Alt[image.Path]; ok {
+			images[i].Alt = alt
+		}
+	}
+	var altImages []markdown.Image
+	for _, path := range slices.Sorted(maps.Keys(en.FrontMatter.Alt)) {
+		if !slices.ContainsFunc(images, func(image markdown.Image) bool { return image.Path == path }) {
+			altImages = append(altImages, markdown.Image{Path: path, Alt: en.FrontMatter.Alt[path]})
+		}
+	}
+	return append(images, altImages...), nil
+}
+
+// IsMarkdown returns true if the entry is a Markdown (e.g. Obsidian) entry.
+func (en Entry) IsMarkdown() bool {
+	return markdown.IsMarkdown(en.Name())
 }
 
 // Returns the Name, e.g. foo.bar.baz from /path/foo.bar.baz.TIMESTAMP.posted
@@ -133,34 +225,41 @@ func (en *Entry) Name() string {
 	return strings.Join(parts[:offset], ".")
 }
 
-// Returns the content and also checks for the size limit
-func (en Entry) ContentWithLimit(sizeLimit int) (string, []string, error) {
-	content, urls, err := en.Content()
+// Returns the content variant for the platform and also checks for the size limit,
+// as counted by the platform (e.g. an emoji is one character).
+func (en Entry) ContentWithLimit(platform string, sizeLimit int, filters []config.ContentFilter) (string, []string, error) {
+	content, urls, err := en.ContentFor(platform, filters)
 	if err != nil {
 		return "", urls, err
 	}
-	if len(content) > sizeLimit {
-		err := fmt.Errorf("%w (%d > %d)", ErrSizeLimitExceeded, len(content), sizeLimit)
+	if length := textlen.Count(platform, content); length > sizeLimit {
+		err := fmt.Errorf("%w (%d > %d)", ErrSizeLimitExceeded, length, sizeLimit)
 		if err2 := prompt.Acknowledge("You need to shorten the content as "+err.Error(), content); err2 != nil {
 			return "", urls, errors.Join(err, err2)
 		}
 		if err2 := en.Edit(); err2 != nil {
 			return "", urls, errors.Join(err, err2)
 		}
-		return en.ContentWithLimit(sizeLimit)
+		return en.ContentWithLimit(platform, sizeLimit, filters)
 	}
 	return content, urls, nil
 }
 
+// MarkPosted marks the queued entry as posted. A posted evergreen entry can be
+// marked as posted again, which records a repost (see Reposts) but keeps the time
+// of its first post.
 func (en *Entry) MarkPosted() error {
 	if en.State == Inboxed {
 		return errors.New("entry still inboxed, can not mark as posted")
 	}
-	if en.State != Queued {
+	if en.State == Posted && !en.HasTag("evergreen") {
+		return errors.New("entry is already posted")
+	}
+	if en.State != Queued && en.State != Posted {
 		return errors.New("entry is not queued")
 	}
 	if en.State == Posted {
-		return errors.New("entry is already posted")
+		return en.recordRepost()
 	}
 	newPath, err := timestamp.UpdateInFilename(strings.TrimSuffix(en.Path, ".queued")+".posted", -2)
 	if err != nil {
@@ -169,20 +268,38 @@ func (en *Entry) MarkPosted() error {
 	if err := os.Rename(en.Path, newPath); err != nil {
 		return err
 	}
+	if err := journal.Record(journal.Op{Kind: journal.Post, From: en.Path, To: newPath}); err != nil {
+		return err
+	}
 	en.State = Posted
 	return nil
 }
 
-func (en Entry) HasHashtags() (bool, error) {
-	content, err := oi.SlurpAndTrim(en.Path)
+// HasDefaultContent returns true if the entry has got a default content, which
+// is posted to all platforms without a variant of their own.
+func (en Entry) HasDefaultContent() (bool, error) {
+	variants, err := en.variants()
 	if err != nil {
 		return false, err
 	}
-	matched, err := regexp.MatchString(`#\w+`, content)
+	return variants[defaultVariant] != "", nil
+}
+
+// HasHashtags returns true if every content variant has got hashtags.
+func (en Entry) HasHashtags() (bool, error) {
+	variants, err := en.variants()
 	if err != nil {
 		return false, err
 	}
-	return matched, nil
+	if variants[defaultVariant] == "" {
+		return false, nil
+	}
+	for _, content := range variants {
+		if !hashtagRE.MatchString(content) {
+			return false, nil
+		}
+	}
+	return true, nil
 }
 
 func (en Entry) HasTag(tag string) bool {
@@ -212,12 +329,108 @@ func (en Entry) FileAction(question string) error {
 
 func (en Entry) extractTags(parts []string) {
 	for _, part := range parts {
-		if slices.Contains(validTags, part) || strings.HasPrefix(part, "share:") {
+		if IsTag(part) {
 			en.Tags[part] = struct{}{}
 		}
 	}
 }
 
+// IsTag returns true if the file name part is a tag, e.g. prio or share:mastodon.
+func IsTag(part string) bool {
+	return slices.Contains(validTags, part) || strings.HasPrefix(part, "share:") ||
+		strings.HasPrefix(part, seriesTagPrefix)
+}
+
+// Series returns the name and the part number of the series the entry belongs to,
+// according to the series:NAME:N tag.
+func (en Entry) Series() (string, int, bool) {
+	for tag := range en.Tags {
+		parts := strings.Split(strings.TrimPrefix(tag, seriesTagPrefix), ":")
+		if !strings.HasPrefix(tag, seriesTagPrefix) || len(parts) != 2 || parts[0] == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(parts[1]); err == nil && n > 0 {
+			return parts[0], n, true
+		}
+	}
+	return "", 0, false
+}
+
+// Matches returns true if name refers to this entry. The name can be the full
+// name (e.g. foo.prio.txt), the name without tags (foo.txt) or without the
+// tags and the extension (foo).
+func (en Entry) Matches(name string) bool {
+	untagged := en.UntaggedName()
+	return en.Name() == name || untagged == name || strings.TrimSuffix(untagged, filepath.Ext(untagged)) == name
+}
+
+// UntaggedName returns the name of the entry without its tags, e.g. foo.txt for
+// foo.prio.txt. Unlike the file name, it doesn't change when retagging.
+func (en Entry) UntaggedName() string {
+	var parts []string
+	for _, part := range strings.Split(en.Name(), ".") {
+		if !IsTag(part) && part != "extracted" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// Retag returns the entry path with the tags added and removed. The timestamp and
+// the state suffix are preserved so that the new path can be parsed by New again.
+// A removed tag "share" removes all share tags, and an added share tag replaces
+// any existing one.
+func (en Entry) Retag(add, remove []string) (string, error) {
+	for _, tag := range add {
+		if !IsTag(tag) {
+			return "", fmt.Errorf("invalid tag '%s'", tag)
+		}
+	}
+
+	dir, base := filepath.Split(en.Path)
+	parts := strings.Split(base, ".")
+	// Extension, or extension plus STAMP and state.
+	suffixLen := 1
+	if en.State != Inboxed {
+		suffixLen = 3
+	}
+	if len(parts) <= suffixLen {
+		return "", fmt.Errorf("not a valid entry path: %s", en.Path)
+	}
+
+	removed := func(part string) bool {
+		for _, tag := range remove {
+			if part == tag || (tag == "share" && strings.HasPrefix(part, "share:")) {
+				return true
+			}
+		}
+		for _, tag := range add {
+			if strings.HasPrefix(tag, "share:") && strings.HasPrefix(part, "share:") {
+				return true
+			}
+		}
+		return false
+	}
+
+	head := parts[:len(parts)-suffixLen]
+	newParts := make([]string, 0, len(parts)+len(add))
+	for i, part := range head {
+		// Never remove the first part, it is the actual name of the entry.
+		if i > 0 && IsTag(part) && removed(part) {
+			continue
+		}
+		newParts = append(newParts, part)
+	}
+	for _, tag := range add {
+		if !slices.Contains(newParts, tag) {
+			newParts = append(newParts, tag)
+		}
+	}
+	newParts = append(newParts, parts[len(parts)-suffixLen:]...)
+
+	return dir + strings.Join(newParts, "."), nil
+}
+
 func (en Entry) Timestamp() (time.Time, error) {
 	fileInfo, err := os.Stat(en.Path)
 	if err != nil {
@@ -2,11 +2,14 @@ package entry
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"slices"
 	"strings"
 	"testing"
 
+	"codeberg.org/snonux/gos/internal/markdown"
 	"codeberg.org/snonux/gos/internal/timestamp"
 )
 
@@ -178,3 +181,205 @@ func FuzzExtractURLs(f *testing.F) {
 		}
 	})
 }
+
+func TestEntryMatches(t *testing.T) {
+	const path = "/foo/bar/baz.prio.share:mastodon.extracted.txt.20250101-010101.queued"
+	en, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"baz.prio.share:mastodon.extracted.txt", "baz.txt", "baz"} {
+		if !en.Matches(name) {
+			t.Errorf("expected '%s' to match '%s'", name, path)
+		}
+	}
+	for _, name := range []string{"ba", "baz.md", "prio", "bar"} {
+		if en.Matches(name) {
+			t.Errorf("didn't expect '%s' to match '%s'", name, path)
+		}
+	}
+}
+
+func TestEntryRetag(t *testing.T) {
+	table := []struct {
+		path, expected string
+		add, remove    []string
+	}{
+		{"/foo/baz.txt", "/foo/baz.prio.txt", []string{"prio"}, nil},
+		{"/foo/baz.prio.txt", "/foo/baz.txt", nil, []string{"prio"}},
+		{"/foo/baz.prio.txt", "/foo/baz.prio.txt", []string{"prio"}, nil},
+		{"/foo/baz.txt.20250101-010101.queued", "/foo/baz.now.txt.20250101-010101.queued", []string{"now"}, nil},
+		{"/foo/baz.soon.txt.20250101-010101.posted", "/foo/baz.prio.txt.20250101-010101.posted", []string{"prio"}, []string{"soon"}},
+		{"/foo/baz.share:linkedin.txt", "/foo/baz.share:-mastodon.txt", []string{"share:-mastodon"}, nil},
+		{"/foo/baz.share:linkedin.ask.txt", "/foo/baz.ask.txt", nil, []string{"share"}},
+		{"prio.txt", "prio.txt", nil, []string{"prio"}},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.path, func(t *testing.T) {
+			en, err := New(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			newPath, err := en.Retag(tt.add, tt.remove)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if newPath != tt.expected {
+				t.Errorf("expected '%s' but got '%s'", tt.expected, newPath)
+			}
+			if _, err := New(newPath); err != nil {
+				t.Errorf("expected '%s' to be a valid entry path: %v", newPath, err)
+			}
+		})
+	}
+
+	en, err := New("/foo/baz.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := en.Retag([]string{"invalid"}, nil); err == nil {
+		t.Error("expected an error when adding an invalid tag")
+	}
+}
+
+func TestMarkdownContent(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "foo.md.20250101-010101.queued")
+	raw := "---\ntags: [foo]\n---\n\n# Hello\n\nRead **[my post](https://foo.zone/a_b.html)** #foo"
+	if err := os.WriteFile(filePath, []byte(raw), 0644); err != nil {
+		t.Fatal(err)
+	}
+	en, err := New(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !en.IsMarkdown() {
+		t.Fatalf("expected %s to be a Markdown entry", filePath)
+	}
+
+	content, urls, err := en.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = "Hello\n\nRead my post https://foo.zone/a_b.html #foo"
+	if content != expected {
+		t.Errorf("expected content '%s' but got '%s'", expected, content)
+	}
+	if !slices.Equal(urls, []string{"https://foo.zone/a_b.html"}) {
+		t.Errorf("expected the rendered link to be extracted but got %v", urls)
+	}
+}
+
+func TestMarkdownContentFor(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "foo.md.20250101-010101.queued")
+	raw := "Thanks **@[Paul](urn:li:person:123)** for [the review](https://foo.zone) #foo"
+	if err := os.WriteFile(filePath, []byte(raw), 0644); err != nil {
+		t.Fatal(err)
+	}
+	en, err := New(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := map[string]string{
+		"mastodon":         "Thanks Paul for the review https://foo.zone #foo",
+		"linkedin":         "Thanks @[Paul](urn:li:person:123) for the review https://foo.zone #foo",
+		"linkedinorg-acme": "Thanks @[Paul](urn:li:person:123) for the review https://foo.zone #foo",
+	}
+	for platform, expected := range table {
+		content, _, err := en.ContentFor(platform, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content != expected {
+			t.Errorf("expected content '%s' for %s but got '%s'", expected, platform, content)
+		}
+	}
+}
+
+func TestImages(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "foo.md.20250101-010101.queued")
+	raw := "---\nalt:\n  ./b.png: The alt text of b\n  ./c.png: The alt text of c\n---\n\n" +
+		"![A cat](./a.jpg) and ![](./b.png) and again ![The cat](./a.jpg)"
+	if err := os.WriteFile(filePath, []byte(raw), 0644); err != nil {
+		t.Fatal(err)
+	}
+	en, err := New(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	images, err := en.Images()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []markdown.Image{
+		{Path: "./a.jpg", Alt: "A cat"},
+		{Path: "./b.png", Alt: "The alt text of b"},
+		{Path: "./c.png", Alt: "The alt text of c"},
+	}
+	if !slices.Equal(images, expected) {
+		t.Errorf("expected images %v but got %v", expected, images)
+	}
+}
+
+func TestMarkPostedEvergreen(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "db", "platforms", "mastodon")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(dir, "foo.evergreen.txt.20250101-010101.posted")
+	if err := os.WriteFile(filePath, []byte("Hello #foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 2; i++ {
+		en, err := New(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := en.MarkPosted(); err != nil {
+			t.Fatal(err)
+		}
+		// The entry keeps the time of its first post.
+		if _, err := os.Stat(filePath); err != nil {
+			t.Fatal(err)
+		}
+		reposts, err := Reposts(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(reposts["foo.txt"]); got != i {
+			t.Errorf("expected %d reposts but got %d", i, got)
+		}
+	}
+
+	// Retagging the entry keeps its reposts.
+	retagged := filepath.Join(dir, "foo.evergreen.prio.txt.20250101-010101.posted")
+	if err := os.Rename(filePath, retagged); err != nil {
+		t.Fatal(err)
+	}
+	en, err := New(retagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := en.MarkPosted(); err != nil {
+		t.Fatal(err)
+	}
+	reposts, err := Reposts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(reposts["foo.txt"]); got != 3 {
+		t.Errorf("expected 3 reposts after retagging but got %d", got)
+	}
+
+	filePath = filepath.Join(dir, "baz.txt.20250101-010101.posted")
+	if err := os.WriteFile(filePath, []byte("Hello #foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	en, err = New(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := en.MarkPosted(); err == nil {
+		t.Error("expected an error marking a posted non-evergreen entry as posted")
+	}
+}
@@ -0,0 +1,569 @@
+package entry
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/markdown"
+	"codeberg.org/snonux/gos/internal/oi"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FrontMatter is the optional YAML (delimited by ---) or TOML (delimited by +++)
+// block at the beginning of an entry. It is an alternative to the tags in the
+// file name, e.g.:
+//
+//	---
+//	tags: [prio, ask]
+//	share:
+//	  include: [mastodon]
+//	  exclude: [linkedin]
+//	schedule: 2025-01-02 10:00
+//	expires: 2025-02-01
+//	platforms:
+//	  mastodon:
+//	    tags: [now]
+//	alt:
+//	  ./cat.jpg: A cat sleeping on a keyboard
+//	card:
+//	  title: A better title for the link preview
+//	document:
+//	  path: ./slides/talk.pdf
+//	  title: The slides of my talk
+//	---
+type FrontMatter struct {
+	Tags     List  `yaml:"tags" toml:"tags"`
+	Share    Share `yaml:"share" toml:"share"`
+	Schedule Time  `yaml:"schedule" toml:"schedule"` // Don't post before
+	Expires  Time  `yaml:"expires" toml:"expires"`   // Don't post after
+	// Per-platform overrides, e.g. a different schedule time for LinkedIn.
+	Platforms map[string]PlatformFrontMatter `yaml:"platforms" toml:"platforms"`
+	// Alt texts of images, keyed by the image path.
+	Alt map[string]string `yaml:"alt" toml:"alt"`
+	// Overrides of the link card (preview) of the first link.
+	Card Card `yaml:"card" toml:"card"`
+	// The document (PDF) to post, e.g. a slide deck.
+	Document Document `yaml:"document" toml:"document"`
+}
+
+// Document is a document attached to the entry, posted as a LinkedIn document post.
+type Document struct {
+	Path  string `yaml:"path" toml:"path"`   // Local path, relative to the gosDir
+	Title string `yaml:"title" toml:"title"` // Defaults to the file name
+}
+
+// Card holds the overrides of the detected link card, as chosen when posting.
+type Card struct {
+	Title       string `yaml:"title" toml:"title"`
+	Description string `yaml:"description" toml:"description"`
+	Image       string `yaml:"image" toml:"image"` // Image URL or local path
+	None        bool   `yaml:"none" toml:"none"`   // Post without a card
+}
+
+func (c Card) toMap() map[string]any {
+	m := make(map[string]any)
+	if c.Title != "" {
+		m["title"] = c.Title
+	}
+	if c.Description != "" {
+		m["description"] = c.Description
+	}
+	if c.Image != "" {
+		m["image"] = c.Image
+	}
+	if c.None {
+		m["none"] = true
+	}
+	return m
+}
+
+// Share holds the platforms to include and exclude, like the share: tag.
+type Share struct {
+	Include List `yaml:"include" toml:"include"`
+	Exclude List `yaml:"exclude" toml:"exclude"`
+}
+
+// PlatformFrontMatter holds the per-platform front matter overrides.
+type PlatformFrontMatter struct {
+	Tags     List   `yaml:"tags" toml:"tags"`
+	Schedule Time   `yaml:"schedule" toml:"schedule"`
+	Expires  Time   `yaml:"expires" toml:"expires"`
+	Content  string `yaml:"content" toml:"content"` // Content variant of the platform
+}
+
+// List is a list of strings, which can also be written as a single string, e.g.
+// "tags: prio" instead of "tags: [prio]".
+type List []string
+
+func (l *List) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = List{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+func (l *List) UnmarshalTOML(value any) error {
+	switch v := value.(type) {
+	case string:
+		*l = List{v}
+	case []any:
+		for _, elem := range v {
+			*l = append(*l, fmt.Sprint(elem))
+		}
+	default:
+		return fmt.Errorf("expected a string or a list, but got '%v'", value)
+	}
+	return nil
+}
+
+// Time is a date, or date and time, in the local time zone, e.g. 2025-01-02 or
+// 2025-01-02 10:00.
+type Time struct{ time.Time }
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	time.DateOnly,
+}
+
+func (t *Time) UnmarshalYAML(node *yaml.Node) error {
+	return t.parse(node.Value)
+}
+
+func (t *Time) UnmarshalTOML(value any) error {
+	switch v := value.(type) {
+	case time.Time:
+		if strings.HasSuffix(v.Location().String(), "-local") {
+			// TOML local date (time) without a time zone offset.
+			v = time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), 0, time.Local)
+		}
+		t.Time = v
+		return nil
+	case string:
+		return t.parse(v)
+	default:
+		return fmt.Errorf("invalid time '%v'", value)
+	}
+}
+
+func (t *Time) parse(value string) error {
+	if value == "" {
+		return nil
+	}
+	for _, layout := range timeLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid time '%s', expected e.g. 2025-01-02 or 2025-01-02 10:00", value)
+}
+
+// ParseFrontMatter parses the front matter of the content, if any.
+func ParseFrontMatter(content string) (FrontMatter, error) {
+	var fm FrontMatter
+	raw, delim, _ := markdown.SplitFrontMatter(content)
+	switch delim {
+	case "---":
+		if err := yaml.Unmarshal([]byte(raw), &fm); err != nil {
+			return fm, fmt.Errorf("invalid YAML front matter: %w", err)
+		}
+	case "+++":
+		if _, err := toml.Decode(raw, &fm); err != nil {
+			return fm, fmt.Errorf("invalid TOML front matter: %w", err)
+		}
+	}
+	return fm, nil
+}
+
+// tags returns the gos tags of the front matter, including a share tag for the
+// included and excluded platforms. Other tags (e.g. Obsidian ones) are ignored.
+func (fm FrontMatter) tags() []string {
+	var tags []string
+	for _, tag := range fm.Tags {
+		if IsTag(tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if shareTag := fm.Share.tag(); shareTag != "" {
+		tags = append(tags, shareTag)
+	}
+	return tags
+}
+
+func (s Share) tag() string {
+	if len(s.Include) == 0 && len(s.Exclude) == 0 {
+		return ""
+	}
+	parts := append([]string{"share"}, s.Include...)
+	for _, platform := range s.Exclude {
+		parts = append(parts, "-"+platform)
+	}
+	return strings.Join(parts, ":")
+}
+
+// readFrontMatter reads the front matter of the entry file, if the file exists
+// and starts with a front matter delimiter, and merges it into the entry.
+func (en *Entry) readFrontMatter() error {
+	file, err := os.Open(en.Path)
+	if err != nil {
+		// E.g. only the file name is parsed, the entry file doesn't exist.
+		return nil
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			// Log the error but don't fail the operation since we've already read the data
+			colour.Errorln("Error closing file:", err)
+		}
+	}()
+
+	// Only read the whole file when there is a front matter.
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(file, head); err != nil {
+		return nil
+	}
+	if !bytes.HasPrefix(head, []byte("---")) && !bytes.HasPrefix(head, []byte("+++")) {
+		return nil
+	}
+	rest, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	fm, err := ParseFrontMatter(string(head) + string(rest))
+	if err != nil {
+		return fmt.Errorf("%s: %w", en.Path, err)
+	}
+	en.FrontMatter = fm
+	for _, tag := range fm.tags() {
+		en.Tags[tag] = struct{}{}
+	}
+	return nil
+}
+
+// ForPlatform returns the entry with the front matter overrides of the platform
+// applied, e.g. additional tags or a different schedule time.
+func (en Entry) ForPlatform(platform string) Entry {
+	override, ok := en.FrontMatter.Platforms[strings.ToLower(platform)]
+	if !ok {
+		return en
+	}
+	tags := make(map[string]struct{}, len(en.Tags)+len(override.Tags))
+	for tag := range en.Tags {
+		tags[tag] = struct{}{}
+	}
+	for _, tag := range override.Tags {
+		if IsTag(tag) {
+			tags[tag] = struct{}{}
+		}
+	}
+	en.Tags = tags
+	if !override.Schedule.IsZero() {
+		en.FrontMatter.Schedule = override.Schedule
+	}
+	if !override.Expires.IsZero() {
+		en.FrontMatter.Expires = override.Expires
+	}
+	return en
+}
+
+// Eligible returns whether the entry may be posted at the given time, according
+// to the schedule and expiry times of the front matter. If not, the reason is
+// returned as well.
+func (en Entry) Eligible(now time.Time) (bool, string) {
+	if schedule := en.FrontMatter.Schedule; !schedule.IsZero() && now.Before(schedule.Time) {
+		return false, fmt.Sprintf("scheduled for %s", schedule.Format(time.DateTime))
+	}
+	if expires := en.FrontMatter.Expires; !expires.IsZero() && now.After(expires.Time) {
+		return false, fmt.Sprintf("expired since %s", expires.Format(time.DateTime))
+	}
+	return true, ""
+}
+
+// MigrateFrontMatter moves the tags of the file name into the front matter of
+// the content. It returns the new file path and the new content. Any existing
+// front matter is preserved, and new front matter is written as YAML unless the
+// existing front matter is TOML.
+func (en Entry) MigrateFrontMatter(content string) (string, string, error) {
+	parts := strings.Split(filepath.Base(en.Path), ".")
+
+	var tags, remove []string
+	var share Share
+	for _, part := range parts[1:] {
+		if !IsTag(part) {
+			continue
+		}
+		remove = append(remove, part)
+		if !strings.HasPrefix(part, "share:") {
+			tags = append(tags, part)
+			continue
+		}
+		for _, platform := range strings.Split(part, ":")[1:] {
+			if strings.HasPrefix(platform, "-") {
+				share.Exclude = append(share.Exclude, platform[1:])
+			} else {
+				share.Include = append(share.Include, platform)
+			}
+		}
+	}
+	if len(remove) == 0 {
+		return en.Path, content, nil
+	}
+
+	newContent, err := updateFrontMatter(content, func(fm map[string]any) {
+		if len(tags) > 0 {
+			fm["tags"] = mergeList(fm["tags"], tags)
+		}
+		if len(share.Include) > 0 || len(share.Exclude) > 0 {
+			existing, _ := fm["share"].(map[string]any)
+			if existing == nil {
+				existing = make(map[string]any)
+			}
+			if len(share.Include) > 0 {
+				existing["include"] = mergeList(existing["include"], share.Include)
+			}
+			if len(share.Exclude) > 0 {
+				existing["exclude"] = mergeList(existing["exclude"], share.Exclude)
+			}
+			fm["share"] = existing
+		}
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	newPath, err := en.Retag(nil, remove)
+	if err != nil {
+		return "", "", err
+	}
+	return newPath, newContent, nil
+}
+
+// RetagFrontMatter adds and removes the tags in the front matter of the content,
+// like Retag does in the file name, and returns the new content. A removed tag
+// "share" removes the share key, and an added share tag replaces it. The tags of
+// the platform overrides aren't changed.
+func RetagFrontMatter(content string, add, remove []string) (string, error) {
+	var addShare string
+	for _, tag := range add {
+		if !IsTag(tag) {
+			return "", fmt.Errorf("invalid tag '%s'", tag)
+		}
+		if strings.HasPrefix(tag, "share:") {
+			addShare = tag
+		}
+	}
+	return updateFrontMatter(content, func(fm map[string]any) {
+		existing := mergeList(fm["tags"], nil)
+		var tags []string
+		for _, tag := range existing {
+			if !slices.Contains(remove, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		for _, tag := range add {
+			if !strings.HasPrefix(tag, "share:") && !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		switch {
+		case len(tags) == 0:
+			delete(fm, "tags")
+		case !slices.Equal(tags, existing):
+			fm["tags"] = tags
+		}
+
+		if addShare == "" && !slices.Contains(remove, "share") {
+			return
+		}
+		delete(fm, "share")
+		if share := parseShare(addShare); len(share) > 0 {
+			fm["share"] = share
+		}
+	})
+}
+
+// Returns the include and exclude lists of the share tag, e.g. share:ma:-li.
+func parseShare(tag string) map[string]any {
+	share := make(map[string]any)
+	if tag == "" {
+		return share
+	}
+	var include, exclude []string
+	for _, platform := range strings.Split(tag, ":")[1:] {
+		if strings.HasPrefix(platform, "-") {
+			exclude = append(exclude, platform[1:])
+		} else {
+			include = append(include, platform)
+		}
+	}
+	if len(include) > 0 {
+		share["include"] = include
+	}
+	if len(exclude) > 0 {
+		share["exclude"] = exclude
+	}
+	return share
+}
+
+// Merges the values into the existing list, which may also be a single string
+// (see List).
+func mergeList(existing any, values []string) []string {
+	var merged []string
+	switch v := existing.(type) {
+	case string:
+		merged = append(merged, v)
+	case []any:
+		for _, elem := range v {
+			merged = append(merged, fmt.Sprint(elem))
+		}
+	}
+	for _, v := range values {
+		if !slices.Contains(merged, v) {
+			merged = append(merged, v)
+		}
+	}
+	return merged
+}
+
+// SetCard persists the link card overrides in the front matter of the entry file.
+func (en *Entry) SetCard(card Card) error {
+	content, err := os.ReadFile(en.Path)
+	if err != nil {
+		return err
+	}
+	newContent, err := updateFrontMatter(string(content), func(fm map[string]any) {
+		if m := card.toMap(); len(m) > 0 {
+			fm["card"] = m
+		} else {
+			delete(fm, "card")
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if err := oi.WriteFile(en.Path, newContent); err != nil {
+		return err
+	}
+	en.FrontMatter.Card = card
+	return nil
+}
+
+// updateFrontMatter updates the front matter of the content and returns the new
+// content. New front matter is written as YAML unless the existing front matter
+// is TOML. Front matter which becomes empty is removed. For YAML only the keys the
+// update changes are rewritten, so the order of the keys and the comments stay.
+func updateFrontMatter(content string, update func(fm map[string]any)) (string, error) {
+	raw, delim, body := markdown.SplitFrontMatter(content)
+	body = strings.TrimSpace(body)
+	fm := make(map[string]any)
+	orig := make(map[string]any)
+	switch delim {
+	case "+++":
+		if _, err := toml.Decode(raw, &fm); err != nil {
+			return "", fmt.Errorf("invalid TOML front matter: %w", err)
+		}
+	case "---":
+		if err := yaml.Unmarshal([]byte(raw), &fm); err != nil {
+			return "", fmt.Errorf("invalid YAML front matter: %w", err)
+		}
+		// A second copy, as the update may change nested values in place.
+		if err := yaml.Unmarshal([]byte(raw), &orig); err != nil {
+			return "", fmt.Errorf("invalid YAML front matter: %w", err)
+		}
+	default:
+		delim = "---"
+	}
+	update(fm)
+	if len(fm) == 0 {
+		return body + "\n", nil
+	}
+
+	var buf bytes.Buffer
+	if delim == "+++" {
+		if err := toml.NewEncoder(&buf).Encode(fm); err != nil {
+			return "", err
+		}
+	} else {
+		doc, err := updateYAML(raw, orig, fm)
+		if err != nil {
+			return "", err
+		}
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%s\n%s%s\n\n%s\n", delim, buf.String(), delim, body), nil
+}
+
+// Applies the changes from orig to fm to the YAML document of the raw front
+// matter. Unchanged keys keep their nodes, and with them their order and comments.
+func updateYAML(raw string, orig, fm map[string]any) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(raw), &doc); err != nil {
+		return nil, fmt.Errorf("invalid YAML front matter: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	mapping := doc.Content[0]
+
+	var content []*yaml.Node
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if _, ok := fm[mapping.Content[i].Value]; ok {
+			content = append(content, mapping.Content[i], mapping.Content[i+1])
+		}
+	}
+	mapping.Content = content
+
+	keys := make([]string, 0, len(fm))
+	for key := range fm {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if old, ok := orig[key]; ok && reflect.DeepEqual(old, fm[key]) {
+			continue
+		}
+		var value yaml.Node
+		if err := value.Encode(fm[key]); err != nil {
+			return nil, err
+		}
+		if i := yamlKey(mapping, key); i >= 0 {
+			mapping.Content[i+1] = &value
+			continue
+		}
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, &value)
+	}
+	return &doc, nil
+}
+
+// Returns the index of the key node in the YAML mapping, or -1.
+func yamlKey(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
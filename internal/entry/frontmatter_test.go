@@ -0,0 +1,215 @@
+package entry
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFrontMatter(t *testing.T) {
+	table := map[string]string{
+		"yaml.txt": `---
+tags: [prio, obsidian]
+share:
+  include: [ma]
+  exclude: linkedin
+schedule: 2025-01-02 10:00
+expires: 2025-02-01
+platforms:
+  mastodon:
+    tags: [now]
+    schedule: 2025-01-03
+alt:
+  ./cat.jpg: A cat
+document:
+  path: ./talk.pdf
+---
+
+Hello world #foo`,
+		"toml.md": `+++
+tags = ["prio", "obsidian"]
+schedule = "2025-01-02 10:00"
+expires = 2025-02-01
+
+[share]
+include = ["ma"]
+exclude = "linkedin"
+
+[platforms.mastodon]
+tags = ["now"]
+schedule = 2025-01-03
+
+[alt]
+"./cat.jpg" = "A cat"
+
+[document]
+path = "./talk.pdf"
++++
+
+Hello world #foo`,
+	}
+
+	for name, content := range table {
+		t.Run(name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), strings.Replace(name, ".", ".ask.", 1))
+			if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			en, err := New(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, tag := range []string{"ask", "prio", "share:ma:-linkedin"} {
+				if !en.HasTag(tag) {
+					t.Errorf("expected tag %s in %v", tag, en.Tags)
+				}
+			}
+			if en.HasTag("obsidian") || en.HasTag("now") {
+				t.Errorf("unexpected tags in %v", en.Tags)
+			}
+			if en.FrontMatter.Alt["./cat.jpg"] != "A cat" {
+				t.Errorf("expected alt text but got %v", en.FrontMatter.Alt)
+			}
+			if en.FrontMatter.Document.Path != "./talk.pdf" {
+				t.Errorf("expected document but got %v", en.FrontMatter.Document)
+			}
+
+			schedule := time.Date(2025, 1, 2, 10, 0, 0, 0, time.Local)
+			if !en.FrontMatter.Schedule.Equal(schedule) {
+				t.Errorf("expected schedule %v but got %v", schedule, en.FrontMatter.Schedule)
+			}
+			if ok, _ := en.Eligible(schedule.Add(-time.Minute)); ok {
+				t.Error("expected entry not to be eligible before its schedule time")
+			}
+			if ok, _ := en.Eligible(schedule); !ok {
+				t.Error("expected entry to be eligible at its schedule time")
+			}
+			if ok, _ := en.Eligible(time.Date(2025, 2, 2, 0, 0, 0, 0, time.Local)); ok {
+				t.Error("expected entry not to be eligible after its expiry time")
+			}
+
+			mastodon := en.ForPlatform("Mastodon")
+			if !mastodon.HasTag("now") || en.HasTag("now") {
+				t.Errorf("expected now tag only for mastodon but got %v and %v", mastodon.Tags, en.Tags)
+			}
+			if ok, _ := mastodon.Eligible(schedule); ok {
+				t.Error("expected mastodon schedule override")
+			}
+
+			got, _, err := en.Content()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != "Hello world #foo" {
+				t.Errorf("expected content without front matter but got '%s'", got)
+			}
+		})
+	}
+}
+
+func TestFrontMatterInvalid(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "foo.txt")
+	if err := os.WriteFile(filePath, []byte("---\nschedule: tomorrow\n---\nHello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(filePath); err == nil {
+		t.Error("expected an error for an invalid schedule time")
+	}
+}
+
+func TestMigrateFrontMatter(t *testing.T) {
+	en, err := New("gosdir/db/foo.prio.share:mastodon:-linkedin.extracted.txt.20250101-010101.queued")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPath, newContent, err := en.MigrateFrontMatter("---\ntags: [obsidian]\ntitle: Foo\n---\n\nHello world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "gosdir/db/foo.extracted.txt.20250101-010101.queued"; newPath != expected {
+		t.Errorf("expected path %s but got %s", expected, newPath)
+	}
+
+	fm, err := ParseFrontMatter(newContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(fm.Tags, ",") != "obsidian,prio" {
+		t.Errorf("expected tags obsidian,prio but got %v", fm.Tags)
+	}
+	if fm.Share.tag() != "share:mastodon:-linkedin" {
+		t.Errorf("expected share:mastodon:-linkedin but got %v", fm.Share)
+	}
+	if !strings.Contains(newContent, "title: Foo") || !strings.HasSuffix(newContent, "\n\nHello world\n") {
+		t.Errorf("expected the title and body to be preserved but got '%s'", newContent)
+	}
+}
+
+func TestMigrateFrontMatterKeepsLayout(t *testing.T) {
+	en, err := New("gosdir/db/foo.prio.txt.20250101-010101.queued")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "---\ntitle: Foo # The title\n# The tags\ntags: obsidian\nauthor: Paul\n---\n\nHello world"
+	_, newContent, err := en.MigrateFrontMatter(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "---\ntitle: Foo # The title\n# The tags\ntags:\n  - obsidian\n  - prio\nauthor: Paul\n---\n\nHello world\n"
+	if newContent != expected {
+		t.Errorf("expected\n%q\nbut got\n%q", expected, newContent)
+	}
+}
+
+func TestSetCard(t *testing.T) {
+	dir := t.TempDir()
+	table := map[string]struct {
+		content, expected string
+	}{
+		"plain.txt": {"Hello https://foo.zone #foo\n",
+			"---\ncard:\n  none: true\n  title: Foo\n---\n\nHello https://foo.zone #foo\n"},
+		"yaml.md": {"---\ntags: [prio]\n---\n\nHello https://foo.zone #foo\n",
+			"---\ntags: [prio]\ncard:\n  none: true\n  title: Foo\n---\n\nHello https://foo.zone #foo\n"},
+		"toml.md": {"+++\ntags = [\"prio\"]\n+++\n\nHello https://foo.zone #foo\n",
+			"+++\ntags = [\"prio\"]\n\n[card]\n  none = true\n  title = \"Foo\"\n+++\n\nHello https://foo.zone #foo\n"},
+	}
+	for name, tt := range table {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		en, err := New(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := en.SetCard(Card{Title: "Foo", None: true}); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.expected {
+			t.Errorf("%s: expected\n%q\nbut got\n%q", name, tt.expected, string(data))
+		}
+		if en, err = New(path); err != nil {
+			t.Fatal(err)
+		}
+		if card := en.FrontMatter.Card; card.Title != "Foo" || !card.None {
+			t.Errorf("%s: expected the card to be read back, got %+v", name, card)
+		}
+
+		// Resetting the card removes it again.
+		if err := en.SetCard(Card{}); err != nil {
+			t.Fatal(err)
+		}
+		if data, err = os.ReadFile(path); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "card") {
+			t.Errorf("%s: expected no card but got %q", name, string(data))
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package entry
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/oi"
+	"codeberg.org/snonux/gos/internal/timestamp"
+)
+
+// The reposts of posted evergreen entries, so that the entries keep the time of
+// their first post. They are keyed by the platform and the untagged name of the
+// entry, so that retagging keeps them, e.g. {"mastodon": {"foo.txt": ["20260101-010101"]}}.
+type repostLog map[string]map[string][]string
+
+// The path of ./db/reposts.json for the ./db/platforms/PLATFORM directory.
+func repostLogPath(platformDir string) string {
+	return filepath.Join(filepath.Dir(filepath.Dir(platformDir)), "reposts.json")
+}
+
+func readRepostLog(path string) (repostLog, error) {
+	log := make(repostLog)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return log, nil
+	}
+	if err != nil {
+		return log, err
+	}
+	return log, json.Unmarshal(data, &log)
+}
+
+// Reposts returns the times of the reposts of the posted entries in the platform
+// directory, keyed by the untagged name of the entry (see Entry.UntaggedName).
+func Reposts(platformDir string) (map[string][]time.Time, error) {
+	log, err := readRepostLog(repostLogPath(platformDir))
+	if err != nil {
+		return nil, err
+	}
+	reposts := make(map[string][]time.Time)
+	for name, stamps := range log[filepath.Base(platformDir)] {
+		for _, stamp := range stamps {
+			t, err := timestamp.Parse(stamp)
+			if err != nil {
+				return nil, err
+			}
+			reposts[name] = append(reposts[name], t)
+		}
+	}
+	return reposts, nil
+}
+
+// Records a repost of the posted entry now.
+func (en Entry) recordRepost() error {
+	platformDir := filepath.Dir(en.Path)
+	path := repostLogPath(platformDir)
+	log, err := readRepostLog(path)
+	if err != nil {
+		return err
+	}
+	platform := filepath.Base(platformDir)
+	if log[platform] == nil {
+		log[platform] = make(map[string][]string)
+	}
+	name := en.UntaggedName()
+	log[platform][name] = append(log[platform][name], timestamp.Now())
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	return oi.WriteFile(path, string(data))
+}
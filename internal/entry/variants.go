@@ -0,0 +1,67 @@
+package entry
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"codeberg.org/snonux/gos/internal/config"
+)
+
+// A variant section starts with a line like "--- mastodon ---" and lasts until
+// the next section or the end of the content. The "default" section (or the
+// content before the first section) is used for all other platforms. Only known
+// platform names start a section, other lines like "--- Update ---" are content.
+var variantSectionRE = regexp.MustCompile(`^---\s*([A-Za-z][\w-]*)\s*---\s*$`)
+
+const defaultVariant = "default"
+
+// splitVariants splits the content (without front matter) into its per-platform
+// variants, keyed by the lower case platform name.
+func splitVariants(content string) map[string]string {
+	var (
+		variants = make(map[string]string)
+		current  = defaultVariant
+		lines    []string
+	)
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(lines, "\n"))
+		if text != "" || current != defaultVariant {
+			variants[current] = text
+		}
+		lines = nil
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if sub := variantSectionRE.FindStringSubmatch(strings.TrimRight(line, "\r")); sub != nil && isVariantName(sub[1]) {
+			flush()
+			current = strings.ToLower(sub[1])
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return variants
+}
+
+// Reports whether the name is the default or a platform, e.g. mastodon or
+// linkedinorg-acme.
+func isVariantName(name string) bool {
+	name = strings.ToLower(name)
+	if name == defaultVariant {
+		return true
+	}
+	org, isOrg := strings.CutPrefix(name, config.LinkedInOrgPrefix)
+	return (isOrg && org != "") || slices.Contains(config.Networks, name)
+}
+
+// variant returns the variant of the platform, or the default one.
+func variant(variants map[string]string, platform string) string {
+	if text, ok := variants[strings.ToLower(platform)]; ok {
+		return text
+	}
+	// E.g. the linkedin variant for a LinkedIn organisation page.
+	if text, ok := variants[config.Network(platform)]; ok {
+		return text
+	}
+	return variants[defaultVariant]
+}
@@ -0,0 +1,79 @@
+package entry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestContentFor(t *testing.T) {
+	content := `---
+platforms:
+  xcom:
+    content: "Short one #x"
+---
+Default text #foo
+
+--- Mastodon ---
+Short text #foo #bar
+
+--- linkedin ---
+A much longer and more formal text #foo`
+
+	filePath := filepath.Join(t.TempDir(), "foo.txt")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	en, err := New(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := map[string]string{
+		"default":  "Default text #foo",
+		"mastodon": "Short text #foo #bar",
+		"linkedin": "A much longer and more formal text #foo",
+		"xcom":     "Short one #x",
+		"noop":     "Default text #foo",
+		// LinkedIn organisation pages use the linkedin variant.
+		"linkedinorg-acme": "A much longer and more formal text #foo",
+	}
+	for platform, expected := range table {
+		got, _, err := en.ContentFor(platform, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != expected {
+			t.Errorf("expected '%s' for %s but got '%s'", expected, platform, got)
+		}
+	}
+
+	if ok, err := en.HasHashtags(); err != nil || !ok {
+		t.Errorf("expected all variants to have hashtags: %v", err)
+	}
+}
+
+func TestHasHashtagsVariants(t *testing.T) {
+	table := map[string]bool{
+		"Hello #foo":                                     true,
+		"Hello":                                          false,
+		"--- mastodon ---\nHello #foo":                   false,
+		"Hello #foo\n--- mastodon ---\nHello":            false,
+		"Hello\n--- mastodon ---\nHello #foo":            false,
+		"Hello #foo\n\n---\n\nA horizontal rule, #bar\n": true,
+		"Hello #foo\n--- Update ---\nStill the default":  true,
+	}
+	for content, expected := range table {
+		filePath := filepath.Join(t.TempDir(), "foo.txt")
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		en, err := New(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := en.HasHashtags(); err != nil || ok != expected {
+			t.Errorf("expected %v for '%s' but got %v (%v)", expected, content, ok, err)
+		}
+	}
+}
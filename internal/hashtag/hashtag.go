@@ -0,0 +1,163 @@
+// Package hashtag manages the hashtags of the posted content: it appends the
+// configured hashtags, rewrites them to their known CamelCase spelling, enforces
+// a maximum count per platform and suggests hashtags learned from the posted
+// entries.
+package hashtag
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/config"
+)
+
+var (
+	// A hashtag starts a word, so that URL fragments (foo.zone/#bar) aren't hashtags.
+	hashtagRE = regexp.MustCompile(`(?:^|[\s(])(#[\p{L}\p{N}_]+)`)
+	// The hashtags at the very end of the content, e.g. "... #foo #bar".
+	trailingRE = regexp.MustCompile(`(?:^|\s)((?:#[\p{L}\p{N}_]+\s*)+)$`)
+)
+
+// Filter returns the content filter which applies the configured hashtag management.
+// The spellings of the posted entries are loaded once, on first use.
+func Filter(args config.Args) config.ContentFilter {
+	var (
+		once      sync.Once
+		spellings map[string]string
+	)
+	return func(tags []string, platform, content string) string {
+		conf := args.Config.Hashtags
+		if conf.CamelCase {
+			once.Do(func() {
+				history, err := LoadHistory(args.GosDir)
+				if err != nil {
+					colour.Infoln("Unable to learn hashtag spellings:", err)
+				}
+				spellings = history.Spellings(conf.Spellings)
+			})
+		}
+		return Apply(conf, spellings, platform, tags, content)
+	}
+}
+
+// Apply applies the hashtag management to the content of the platform. It appends
+// the hashtags configured for the platform and the entry tags, rewrites all
+// hashtags to their known CamelCase spelling (if enabled), drops trailing hashtags exceeding the
+// maximum count and, for LinkedIn, moves the trailing hashtags into a footer.
+func Apply(conf config.Hashtags, spellings map[string]string, platform string, tags []string, content string) string {
+	add := slices.Clone(forPlatform(conf.Platforms, platform))
+	for _, tag := range tags {
+		add = append(add, conf.Tags[tag]...)
+	}
+	content = appendHashtags(content, add)
+	if conf.CamelCase {
+		content = camelCase(content, spellings)
+	}
+	if maxCount := forPlatform(conf.Max, platform); maxCount > 0 {
+		content = limit(content, maxCount)
+	}
+	if config.Network(platform) == "linkedin" {
+		content = footer(content)
+	}
+	return content
+}
+
+// Returns the setting of the platform, falling back to the one of its network,
+// e.g. linkedin for a LinkedIn organisation page.
+func forPlatform[T any](settings map[string]T, platform string) T {
+	if setting, ok := settings[platform]; ok {
+		return setting
+	}
+	return settings[config.Network(platform)]
+}
+
+// Extract returns all hashtags of the content, in order of appearance.
+func Extract(content string) []string {
+	var hashtags []string
+	for _, m := range hashtagRE.FindAllStringSubmatch(content, -1) {
+		hashtags = append(hashtags, m[1])
+	}
+	return hashtags
+}
+
+// Count returns the number of hashtags of the content.
+func Count(content string) int {
+	return len(Extract(content))
+}
+
+// Appends the hashtags not yet used by the content to its trailing hashtags, or
+// as a new paragraph.
+func appendHashtags(content string, hashtags []string) string {
+	used := make(map[string]struct{})
+	for _, hashtag := range Extract(content) {
+		used[strings.ToLower(hashtag)] = struct{}{}
+	}
+	var missing []string
+	for _, hashtag := range hashtags {
+		if !strings.HasPrefix(hashtag, "#") {
+			hashtag = "#" + hashtag
+		}
+		if _, ok := used[strings.ToLower(hashtag)]; ok {
+			continue
+		}
+		used[strings.ToLower(hashtag)] = struct{}{}
+		missing = append(missing, hashtag)
+	}
+	switch {
+	case len(missing) == 0:
+		return content
+	case strings.TrimSpace(content) == "":
+		return strings.Join(missing, " ")
+	case trailingRE.MatchString(content):
+		return strings.TrimRightFunc(content, unicode.IsSpace) + " " + strings.Join(missing, " ")
+	default:
+		return strings.TrimRightFunc(content, unicode.IsSpace) + "\n\n" + strings.Join(missing, " ")
+	}
+}
+
+// Rewrites the hashtags to their known spelling, e.g. #opensource becomes
+// #OpenSource. Hashtags without a known spelling are kept as written, as the
+// word boundaries can't be guessed.
+func camelCase(content string, spellings map[string]string) string {
+	return hashtagRE.ReplaceAllStringFunc(content, func(match string) string {
+		i := strings.Index(match, "#")
+		if spelling, ok := spellings[strings.ToLower(match[i:])]; ok {
+			return match[:i] + spelling
+		}
+		return match
+	})
+}
+
+// Drops trailing hashtags until there are at most maxCount hashtags. Hashtags
+// within the text are kept, as dropping them would garble it.
+func limit(content string, maxCount int) string {
+	excess := Count(content) - maxCount
+	loc := trailingRE.FindStringSubmatchIndex(content)
+	if excess <= 0 || loc == nil {
+		return content
+	}
+	trailing := strings.Fields(content[loc[2]:loc[3]])
+	keep := max(len(trailing)-excess, 0)
+	text := strings.TrimRightFunc(content[:loc[2]], unicode.IsSpace)
+	if keep == 0 {
+		return text
+	}
+	return text + content[len(text):loc[2]] + strings.Join(trailing[:keep], " ")
+}
+
+// Moves the trailing hashtags into a footer paragraph of their own.
+func footer(content string) string {
+	loc := trailingRE.FindStringSubmatchIndex(content)
+	if loc == nil {
+		return content
+	}
+	text := strings.TrimRightFunc(content[:loc[2]], unicode.IsSpace)
+	if text == "" {
+		return content
+	}
+	return text + "\n\n" + strings.Join(strings.Fields(content[loc[2]:loc[3]]), " ")
+}
@@ -0,0 +1,94 @@
+package hashtag
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"codeberg.org/snonux/gos/internal/config"
+)
+
+func TestExtract(t *testing.T) {
+	got := Extract("Hello #foo, see https://foo.zone/#bar (#baz)\n#Grüße")
+	expected := []string{"#foo", "#baz", "#Grüße"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+}
+
+func TestApply(t *testing.T) {
+	conf := config.Hashtags{
+		Platforms: map[string][]string{"mastodon": {"#gos"}, "linkedin": {"Gos"}},
+		Tags:      map[string][]string{"evergreen": {"#Throwback"}},
+		Max:       map[string]int{"mastodon": 3},
+	}
+	camelCase := conf
+	camelCase.CamelCase = true
+
+	table := []struct {
+		conf     config.Hashtags
+		platform string
+		tags     []string
+		content  string
+		expected string
+	}{
+		{conf, "noop", nil, "Hello #foo", "Hello #foo"},
+		{conf, "mastodon", nil, "Hello #foo", "Hello #foo #gos"},
+		{conf, "mastodon", nil, "Hello #GOS", "Hello #GOS"},
+		{conf, "mastodon", nil, "Hello world", "Hello world\n\n#gos"},
+		{conf, "mastodon", []string{"evergreen"}, "Hello #foo", "Hello #foo #gos #Throwback"},
+		{conf, "mastodon", []string{"evergreen"}, "Hello", "Hello\n\n#gos #Throwback"},
+		{conf, "mastodon", nil, "Hello #a #b #c #d", "Hello #a #b #c"},
+		{conf, "mastodon", nil, "#a #b #c and #d", "#a #b #c and"},
+		{conf, "linkedin", nil, "Hello #foo", "Hello\n\n#foo #Gos"},
+		{conf, "linkedin", nil, "#foo", "#foo #Gos"},
+		{camelCase, "noop", nil, "Hello #opensource and #golang #BSD", "Hello #OpenSource and #golang #BSD"},
+	}
+	spellings := map[string]string{"#opensource": "#OpenSource"}
+	for _, tt := range table {
+		if got := Apply(tt.conf, spellings, tt.platform, tt.tags, tt.content); got != tt.expected {
+			t.Errorf("expected %q for %q on %s but got %q", tt.expected, tt.content, tt.platform, got)
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	gosDir := t.TempDir()
+	files := map[string]string{
+		"mastodon/a.txt.20250101-000000.posted": "Running #FreeBSD on #OpenSource hardware",
+		"linkedin/a.txt.20250101-000000.posted": "Running #FreeBSD on #OpenSource hardware",
+		"mastodon/b.txt.20250102-000000.posted": "More #freebsd and #golang",
+		"mastodon/c.txt.20250103-000000.posted": "Even more #FreeBSD",
+		"mastodon/d.txt.20250104-000000.queued": "Queued #Queued",
+	}
+	for name, content := range files {
+		path := filepath.Join(gosDir, "db", "platforms", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h, err := LoadHistory(gosDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spellings := h.Spellings([]string{"#GoLang"})
+	for key, expected := range map[string]string{"#freebsd": "#FreeBSD", "#opensource": "#OpenSource", "#golang": "#GoLang"} {
+		if spellings[key] != expected {
+			t.Errorf("expected spelling %s for %s but got %s", expected, key, spellings[key])
+		}
+	}
+	if _, ok := spellings["#queued"]; ok {
+		t.Error("expected no spelling learned from queued entries")
+	}
+
+	got := h.Suggest("Trying golang on freebsd, #OpenSource as always, queued")
+	expected := []string{"#FreeBSD", "#golang"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected suggestions %v but got %v", expected, got)
+	}
+}
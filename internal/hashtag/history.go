@@ -0,0 +1,133 @@
+package hashtag
+
+import (
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/entry"
+)
+
+// Maximum number of suggested hashtags.
+const maxSuggestions = 5
+
+var wordRE = regexp.MustCompile(`[\p{L}\p{N}_]+`)
+
+// History is what was learned from the hashtags of the posted entries.
+type History struct {
+	// Number of posted entries using the hashtag, keyed by the lower case hashtag.
+	counts map[string]int
+	// How often each spelling of a hashtag was used, e.g. #OpenSource vs #opensource.
+	spellings map[string]map[string]int
+}
+
+// LoadHistory learns the hashtags of all posted entries of the gosDir. Each entry
+// counts once, even when posted to several platforms.
+func LoadHistory(gosDir string) (History, error) {
+	h := History{counts: make(map[string]int), spellings: make(map[string]map[string]int)}
+	paths, err := filepath.Glob(filepath.Join(gosDir, "db/platforms/*/*.posted"))
+	if err != nil {
+		return h, err
+	}
+	seen := make(map[string]struct{})
+	for _, path := range paths {
+		en, err := entry.New(path)
+		if err != nil {
+			colour.Infoln(err)
+			continue
+		}
+		if _, ok := seen[en.Name()]; ok {
+			continue
+		}
+		seen[en.Name()] = struct{}{}
+		content, _, err := en.Content()
+		if err != nil {
+			return h, err
+		}
+		h.learn(content)
+	}
+	return h, nil
+}
+
+func (h History) learn(content string) {
+	used := make(map[string]struct{})
+	for _, hashtag := range Extract(content) {
+		key := strings.ToLower(hashtag)
+		if h.spellings[key] == nil {
+			h.spellings[key] = make(map[string]int)
+		}
+		h.spellings[key][hashtag]++
+		if _, ok := used[key]; !ok {
+			used[key] = struct{}{}
+			h.counts[key]++
+		}
+	}
+}
+
+// Spellings returns the preferred spelling of each hashtag, keyed by the lower
+// case hashtag. These are the configured ones, otherwise the most used mixed
+// case spelling of the posted entries.
+func (h History) Spellings(configured []string) map[string]string {
+	spellings := make(map[string]string)
+	for key, used := range h.spellings {
+		var best string
+		for spelling, count := range used {
+			if spelling == key {
+				continue // All lower case, nothing to learn from it
+			}
+			if best == "" || count > used[best] || (count == used[best] && spelling < best) {
+				best = spelling
+			}
+		}
+		if best != "" {
+			spellings[key] = best
+		}
+	}
+	for _, spelling := range configured {
+		if !strings.HasPrefix(spelling, "#") {
+			spelling = "#" + spelling
+		}
+		spellings[strings.ToLower(spelling)] = spelling
+	}
+	return spellings
+}
+
+// Suggest returns previously used hashtags matching words of the content which
+// aren't hashtags yet, most used first.
+func (h History) Suggest(content string) []string {
+	used := make(map[string]struct{})
+	for _, hashtag := range Extract(content) {
+		used[strings.ToLower(hashtag)] = struct{}{}
+	}
+	var candidates []string
+	for _, word := range wordRE.FindAllString(strings.ToLower(content), -1) {
+		key := "#" + word
+		if _, ok := used[key]; ok || h.counts[key] == 0 {
+			continue
+		}
+		used[key] = struct{}{}
+		candidates = append(candidates, key)
+	}
+	slices.SortStableFunc(candidates, func(a, b string) int {
+		return h.counts[b] - h.counts[a]
+	})
+	if len(candidates) > maxSuggestions {
+		candidates = candidates[:maxSuggestions]
+	}
+	spellings := h.Spellings(nil)
+	for i, key := range candidates {
+		if spelling, ok := spellings[key]; ok {
+			candidates[i] = spelling
+		}
+	}
+	return candidates
+}
+
+// PrintSuggestions prints the suggested hashtags for the content, if any.
+func (h History) PrintSuggestions(content string) {
+	if suggestions := h.Suggest(content); len(suggestions) > 0 {
+		colour.Infofln("Suggested hashtags: %s", strings.Join(suggestions, " "))
+	}
+}
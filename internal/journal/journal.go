@@ -0,0 +1,324 @@
+// Package journal records all filesystem transitions gos performs on entries in
+// an append-only journal, so that they can be undone later.
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/oi"
+)
+
+// Kind is the kind of a filesystem transition.
+type Kind string
+
+const (
+	// Extract is the extraction of inline tags into a new file name (From -> To).
+	// The original content is kept in the operation.
+	Extract Kind = "extract"
+	// Migrate is the migration of file name tags into the front matter (From -> To).
+	// The original content is kept in the operation.
+	Migrate Kind = "migrate"
+	// Retag is the change of the tags in the front matter of an entry, which may
+	// also be renamed (From -> To). The original content is kept in the operation.
+	Retag Kind = "retag"
+	// Queue is the move of an inboxed entry into ./db (From -> To).
+	Queue Kind = "queue"
+	// QueuePlatform is the copy of a queued entry into a platform queue (From -> To).
+	QueuePlatform Kind = "queuePlatform"
+	// Post is the rename of a queued entry to posted (From -> To).
+	Post Kind = "post"
+	// Rename is any other rename of an entry, e.g. due to retagging (From -> To).
+	Rename Kind = "rename"
+	// Copy is any other copy of an entry, e.g. back to the inbox (From -> To).
+	Copy Kind = "copy"
+	// Trash is the move of an entry into the trashbin (From -> To).
+	Trash Kind = "trash"
+	// Restore is the move of an entry out of the trashbin (From -> To).
+	Restore Kind = "restore"
+	// Purge is the deletion of a file (From) purged from the trashbin. It can not
+	// be undone, so that purged entries don't stay in the journal forever.
+	Purge Kind = "purge"
+	// Undo marks the operation with the sequence number Undoes as undone.
+	Undo Kind = "undo"
+)
+
+// ErrNothingToUndo is returned when there are no operations left to undo.
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// Op is a single filesystem transition recorded in the journal.
+type Op struct {
+	Seq     int       `json:"seq"`
+	Time    time.Time `json:"time"`
+	Kind    Kind      `json:"kind"`
+	From    string    `json:"from,omitempty"`
+	To      string    `json:"to,omitempty"`
+	Content string    `json:"content,omitempty"`
+	Undoes  int       `json:"undoes,omitempty"`
+}
+
+// The journal file, only set when the journal is enabled.
+var journalPath string
+
+// Enable enables recording into the journal of the gosDir.
+func Enable(gosDir string) {
+	journalPath = Path(gosDir)
+}
+
+// Path returns the path of the journal of the gosDir.
+func Path(gosDir string) string {
+	return filepath.Join(gosDir, "db", "journal.jsonl")
+}
+
+func (op Op) String() string {
+	switch op.Kind {
+	case Purge:
+		return fmt.Sprintf("#%d %s %s: %s", op.Seq, op.Time.Format(time.DateTime), op.Kind, op.From)
+	case Undo:
+		return fmt.Sprintf("#%d %s %s: #%d", op.Seq, op.Time.Format(time.DateTime), op.Kind, op.Undoes)
+	default:
+		return fmt.Sprintf("#%d %s %s: %s -> %s", op.Seq, op.Time.Format(time.DateTime), op.Kind, op.From, op.To)
+	}
+}
+
+// Record appends the operation to the journal. It does nothing unless the journal
+// is enabled.
+func Record(op Op) error {
+	if journalPath == "" {
+		return nil
+	}
+	return appendOp(journalPath, op)
+}
+
+// RecordPurge deletes the file for good and records the deletion in the journal.
+func RecordPurge(filePath string) error {
+	if err := Record(Op{Kind: Purge, From: filePath}); err != nil {
+		return err
+	}
+	return os.Remove(filePath)
+}
+
+// Read returns all operations of the journal, the oldest first.
+func Read(path string) ([]Op, error) {
+	var ops []Op
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return ops, nil
+	}
+	if err != nil {
+		return ops, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			// Log the error but don't fail the operation since we've already read the data
+			colour.Errorln("Error closing journal:", err)
+		}
+	}()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var op Op
+		if err := json.Unmarshal(scanner.Bytes(), &op); err != nil {
+			return ops, fmt.Errorf("corrupt journal %s: %w", path, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, scanner.Err()
+}
+
+// Undoable returns the operations which haven't been undone yet, the most recent
+// first. Purges can't be undone.
+func Undoable(ops []Op) []Op {
+	undone := make(map[int]struct{})
+	for _, op := range ops {
+		if op.Kind == Undo {
+			undone[op.Undoes] = struct{}{}
+		}
+	}
+	var results []Op
+	for i := len(ops) - 1; i >= 0; i-- {
+		if _, ok := undone[ops[i].Seq]; ok || ops[i].Kind == Undo || ops[i].Kind == Purge {
+			continue
+		}
+		results = append(results, ops[i])
+	}
+	return results
+}
+
+// UndoLast replays the inverse of the last n operations of the journal of the
+// gosDir, the most recent first. Every undone operation is marked as undone in
+// the journal, so that a subsequent undo continues further back in history.
+func UndoLast(gosDir string, n int) ([]Op, error) {
+	path := Path(gosDir)
+	ops, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	undoable := Undoable(ops)
+	if len(undoable) == 0 {
+		return nil, ErrNothingToUndo
+	}
+	if n > len(undoable) {
+		n = len(undoable)
+	}
+
+	var undone []Op
+	for _, op := range undoable[:n] {
+		colour.Infoln("Undoing", op)
+		if err := undo(op); err != nil {
+			return undone, fmt.Errorf("unable to undo %s: %w", op, err)
+		}
+		if err := appendOp(path, Op{Kind: Undo, Undoes: op.Seq}); err != nil {
+			return undone, err
+		}
+		undone = append(undone, op)
+	}
+	return undone, nil
+}
+
+func undo(op Op) error {
+	switch op.Kind {
+	case Extract, Migrate:
+		if err := restoreContent(op.From, op.Content); err != nil {
+			return err
+		}
+		return os.Remove(op.To)
+	case Retag:
+		if op.To != op.From {
+			if _, err := os.Stat(op.From); err == nil {
+				return fmt.Errorf("%s already exists", op.From)
+			}
+			if err := os.Remove(op.To); err != nil {
+				return err
+			}
+		}
+		return oi.WriteFile(op.From, op.Content)
+	case Post:
+		colour.Warnln("The post of", op.From, "to", filepath.Base(filepath.Dir(op.From)),
+			"can not be undone remotely, please delete it there manually!")
+		return renameBack(op)
+	case Trash:
+		if _, err := os.Stat(op.To); os.IsNotExist(err) {
+			return fmt.Errorf("%s was purged from the trashbin", op.To)
+		}
+		return renameBack(op)
+	case Queue, Rename, Restore:
+		return renameBack(op)
+	case QueuePlatform, Copy:
+		return os.Remove(op.To)
+	default:
+		return fmt.Errorf("unknown journal operation kind '%s'", op.Kind)
+	}
+}
+
+func renameBack(op Op) error {
+	if _, err := os.Stat(op.From); err == nil {
+		return fmt.Errorf("%s already exists", op.From)
+	}
+	return oi.Rename(op.To, op.From)
+}
+
+func restoreContent(filePath, content string) error {
+	if _, err := os.Stat(filePath); err == nil {
+		return fmt.Errorf("%s already exists", filePath)
+	}
+	if err := oi.EnsureParentDir(filePath); err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, []byte(content), 0644)
+}
+
+func appendOp(path string, op Op) error {
+	// Every line is one operation, the sequence number is the line number.
+	seq, err := lastSeq(path)
+	if err != nil {
+		return err
+	}
+	op.Seq = seq + 1
+	if op.Time.IsZero() {
+		op.Time = time.Now()
+	}
+	line, err := json.Marshal(op)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal operation: %w", err)
+	}
+
+	if err := oi.EnsureParentDir(path); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			// Log the error but don't fail the operation since we've already written the data
+			colour.Errorln("Error closing journal:", err)
+		}
+	}()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// Returns the sequence number of the last operation of the journal. Only the tail
+// of the journal is read, so that appending doesn't slow down as it grows.
+func lastSeq(path string) (int, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			// Log the error but don't fail the operation since we've already read the data
+			colour.Errorln("Error closing journal:", err)
+		}
+	}()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	// Read ever larger chunks from the end, until the last line is complete.
+	var (
+		offset = info.Size()
+		chunk  = int64(4096)
+		tail   []byte
+	)
+	for offset > 0 {
+		n := min(chunk, offset)
+		offset -= n
+		buf := make([]byte, n, n+int64(len(tail)))
+		if _, err := file.ReadAt(buf, offset); err != nil {
+			return 0, err
+		}
+		tail = append(buf, tail...)
+		chunk *= 2
+
+		lines := bytes.TrimRight(tail, "\n")
+		i := bytes.LastIndexByte(lines, '\n')
+		if i < 0 && offset > 0 {
+			continue
+		}
+		line := lines[i+1:]
+		if len(line) == 0 {
+			return 0, nil
+		}
+		var op Op
+		if err := json.Unmarshal(line, &op); err != nil {
+			return 0, fmt.Errorf("corrupt journal %s: %w", path, err)
+		}
+		return op.Seq, nil
+	}
+	return 0, nil
+}
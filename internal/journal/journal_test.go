@@ -0,0 +1,189 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectContent(t *testing.T, path, expected string) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != expected {
+		t.Errorf("expected content '%s' in %s but got '%s'", expected, path, string(content))
+	}
+}
+
+func expectMissing(t *testing.T, path string) {
+	t.Helper()
+	if _, err := os.Stat(path); err == nil {
+		t.Errorf("expected %s not to exist", path)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	gosDir := t.TempDir()
+	Enable(gosDir)
+	defer func() { journalPath = "" }()
+
+	var (
+		inboxPath    = filepath.Join(gosDir, "foo.txt")
+		queuedPath   = filepath.Join(gosDir, "db", "foo.txt.20250101-010101.queued")
+		platformPath = filepath.Join(gosDir, "db", "platforms", "mastodon", "foo.txt.20250101-010101.queued")
+		renamedPath  = filepath.Join(gosDir, "db", "foo.prio.txt.20250101-010101.queued")
+	)
+
+	// Simulate queueing an entry to a platform, and then renaming the queued one.
+	writeFile(t, queuedPath, "Hello #world")
+	if err := Record(Op{Kind: Queue, From: inboxPath, To: queuedPath}); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, platformPath, "Hello #world")
+	if err := Record(Op{Kind: QueuePlatform, From: queuedPath, To: platformPath}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(queuedPath, renamedPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := Record(Op{Kind: Rename, From: queuedPath, To: renamedPath}); err != nil {
+		t.Fatal(err)
+	}
+
+	undone, err := UndoLast(gosDir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(undone) != 2 || undone[0].Kind != Rename || undone[1].Kind != QueuePlatform {
+		t.Errorf("expected rename and queuePlatform to be undone, but got %v", undone)
+	}
+	expectContent(t, queuedPath, "Hello #world")
+	expectMissing(t, renamedPath)
+	expectMissing(t, platformPath)
+
+	// A subsequent undo continues further back in history.
+	if _, err := UndoLast(gosDir, 5); err != nil {
+		t.Fatal(err)
+	}
+	expectContent(t, inboxPath, "Hello #world")
+	expectMissing(t, queuedPath)
+
+	if _, err := UndoLast(gosDir, 1); err != ErrNothingToUndo {
+		t.Errorf("expected %v but got %v", ErrNothingToUndo, err)
+	}
+}
+
+func TestUndoExtract(t *testing.T) {
+	gosDir := t.TempDir()
+	Enable(gosDir)
+	defer func() { journalPath = "" }()
+
+	var (
+		origPath = filepath.Join(gosDir, "foo.txt")
+		newPath  = filepath.Join(gosDir, "foo.prio.extracted.txt")
+	)
+	writeFile(t, newPath, "Hello #world")
+	if err := Record(Op{Kind: Extract, From: origPath, To: newPath, Content: "prio Hello #world"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := UndoLast(gosDir, 1); err != nil {
+		t.Fatal(err)
+	}
+	expectContent(t, origPath, "prio Hello #world")
+	expectMissing(t, newPath)
+}
+
+func TestUndoRetag(t *testing.T) {
+	gosDir := t.TempDir()
+	Enable(gosDir)
+	defer func() { journalPath = "" }()
+
+	filePath := filepath.Join(gosDir, "foo.txt")
+	writeFile(t, filePath, "---\ntags: [ask]\n---\n\nHello")
+	if err := Record(Op{Kind: Retag, From: filePath, To: filePath, Content: "---\ntags: [prio]\n---\n\nHello"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := UndoLast(gosDir, 1); err != nil {
+		t.Fatal(err)
+	}
+	expectContent(t, filePath, "---\ntags: [prio]\n---\n\nHello")
+}
+
+func TestRecordDisabled(t *testing.T) {
+	gosDir := t.TempDir()
+	if err := Record(Op{Kind: Rename, From: "a", To: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	expectMissing(t, Path(gosDir))
+}
+
+func TestLastSeq(t *testing.T) {
+	gosDir := t.TempDir()
+	Enable(gosDir)
+	defer func() { journalPath = "" }()
+
+	if seq, err := lastSeq(Path(gosDir)); err != nil || seq != 0 {
+		t.Fatalf("expected 0 for a missing journal but got %d (%v)", seq, err)
+	}
+	// Lines longer than the chunks read from the end of the journal.
+	for i, size := range []int{10, 5000, 100, 20000} {
+		if err := Record(Op{Kind: Extract, From: "a", To: "b", Content: strings.Repeat("x", size)}); err != nil {
+			t.Fatal(err)
+		}
+		seq, err := lastSeq(Path(gosDir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq != i+1 {
+			t.Errorf("expected sequence number %d but got %d", i+1, seq)
+		}
+	}
+}
+
+func TestPurge(t *testing.T) {
+	gosDir := t.TempDir()
+	Enable(gosDir)
+	defer func() { journalPath = "" }()
+
+	var (
+		inboxPath = filepath.Join(gosDir, "foo.txt")
+		trashPath = filepath.Join(gosDir, "db", "trashbin", "inbox", "foo.txt.trash")
+	)
+	writeFile(t, trashPath, "Hello #world")
+	if err := Record(Op{Kind: Trash, From: inboxPath, To: trashPath}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordPurge(trashPath); err != nil {
+		t.Fatal(err)
+	}
+	expectMissing(t, trashPath)
+
+	ops, err := Read(Path(gosDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[1].Kind != Purge || ops[1].Content != "" {
+		t.Fatalf("expected the purge to be recorded without content but got %v", ops)
+	}
+	if undoable := Undoable(ops); len(undoable) != 1 || undoable[0].Kind != Trash {
+		t.Errorf("expected only the trash to be undoable but got %v", undoable)
+	}
+	if _, err := UndoLast(gosDir, 1); err == nil || !strings.Contains(err.Error(), "purged") {
+		t.Errorf("expected an error undoing the trash of a purged entry but got %v", err)
+	}
+}
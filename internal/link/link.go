@@ -0,0 +1,176 @@
+// Package link decorates the links of the posted content per platform, e.g. with
+// utm_source=mastodon, according to the rules of the config file.
+package link
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"codeberg.org/snonux/gos/internal/config"
+)
+
+// The placeholder of query values replaced by the platform name.
+const platformPlaceholder = "{platform}"
+
+var (
+	urlRE = regexp.MustCompile(`(?:https?|ftp)://\S+`)
+	// Query parameters which only track where a click came from. Not ref, which
+	// selects e.g. the branch on GitHub and GitLab.
+	trackingParamRE = regexp.MustCompile(`^(utm_\w+|fbclid|gclid|mc_cid|mc_eid|ref_src)$`)
+)
+
+// IsTracking returns true if the query parameter only tracks where a click came from.
+func IsTracking(param string) bool {
+	return trackingParamRE.MatchString(strings.ToLower(param))
+}
+
+// Clean returns the URL without its tracking query parameters.
+func Clean(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+	query := u.Query()
+	for key := range query {
+		if IsTracking(key) {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Filter returns the content filter which decorates the links with the configured rules.
+func Filter(args config.Args) config.ContentFilter {
+	return func(tags []string, platform, content string) string {
+		return Decorate(args.Config.Links, platform, content)
+	}
+}
+
+// Decorate applies the rules, in order, to all links of the content posted to
+// the platform. Links no rule changes are kept as they are.
+func Decorate(rules []config.LinkRule, platform, content string) string {
+	if len(rules) == 0 {
+		return content
+	}
+	return urlRE.ReplaceAllStringFunc(content, func(match string) string {
+		rawURL := trimURL(match)
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return match
+		}
+		var changed bool
+		for _, rule := range rules {
+			if applies(rule, platform, u) && apply(rule, platform, u) {
+				changed = true
+			}
+		}
+		if !changed {
+			return match
+		}
+		return u.String() + match[len(rawURL):]
+	})
+}
+
+// Returns the URL without the trailing punctuation, which most likely ends the
+// sentence and not the URL. Like the GFM autolinker, a closing parenthesis only
+// ends the URL if it isn't balanced within it, e.g. not in
+// https://en.wikipedia.org/wiki/Go_(programming_language).
+func trimURL(match string) string {
+	for match != "" {
+		switch last := match[len(match)-1]; {
+		case strings.IndexByte(".,;:!?*_~'\"", last) >= 0:
+			match = match[:len(match)-1]
+		case last == ')' && strings.Count(match, ")") > strings.Count(match, "("):
+			match = match[:len(match)-1]
+		default:
+			return match
+		}
+	}
+	return match
+}
+
+func applies(rule config.LinkRule, platform string, u *url.URL) bool {
+	if len(rule.Platforms) > 0 && !slices.Contains(rule.Platforms, platform) &&
+		!slices.Contains(rule.Platforms, config.Network(platform)) {
+		return false
+	}
+	if slices.ContainsFunc(rule.ExcludeDomains, func(domain string) bool { return matchesDomain(u, domain) }) {
+		return false
+	}
+	return len(rule.Domains) == 0 ||
+		slices.ContainsFunc(rule.Domains, func(domain string) bool { return matchesDomain(u, domain) })
+}
+
+// The host is the domain or one of its subdomains, e.g. www.foo.zone is foo.zone.
+func matchesDomain(u *url.URL, domain string) bool {
+	host, domain := strings.ToLower(u.Hostname()), strings.ToLower(domain)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// Applies the rule to the URL, and reports whether it changed anything.
+func apply(rule config.LinkRule, platform string, u *url.URL) bool {
+	orig := *u
+	if rule.HTTPS && u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	if rule.Scheme != "" {
+		u.Scheme = rule.Scheme
+	}
+	if rule.Domain != "" {
+		if port := u.Port(); port != "" {
+			u.Host = rule.Domain + ":" + port
+		} else {
+			u.Host = rule.Domain
+		}
+	}
+	if rule.StripTracking || len(rule.Strip) > 0 || len(rule.Query) > 0 {
+		strip := func(key string) bool {
+			return (rule.StripTracking && IsTracking(key)) || slices.Contains(rule.Strip, key)
+		}
+		set := make(map[string]string, len(rule.Query))
+		for key, value := range rule.Query {
+			set[key] = strings.ReplaceAll(value, platformPlaceholder, platform)
+		}
+		u.RawQuery = editQuery(u.RawQuery, strip, set)
+	}
+	return *u != orig
+}
+
+// Edits the raw query, keeping the order and the encoding of the parameters not
+// stripped or set. Parameters set, but not in the query yet, are appended sorted.
+func editQuery(rawQuery string, strip func(key string) bool, set map[string]string) string {
+	var params []string
+	done := make(map[string]bool)
+	if rawQuery != "" {
+		for _, param := range strings.Split(rawQuery, "&") {
+			rawKey, _, _ := strings.Cut(param, "=")
+			key, err := url.QueryUnescape(rawKey)
+			if err != nil {
+				key = rawKey
+			}
+			value, ok := set[key]
+			switch {
+			case ok && done[key]:
+				continue
+			case ok:
+				param = url.QueryEscape(key) + "=" + url.QueryEscape(value)
+				done[key] = true
+			case strip(key):
+				continue
+			}
+			params = append(params, param)
+		}
+	}
+	var added []string
+	for key, value := range set {
+		if !done[key] {
+			added = append(added, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+	sort.Strings(added)
+	return strings.Join(append(params, added...), "&")
+}
@@ -0,0 +1,61 @@
+package link
+
+import (
+	"testing"
+
+	"codeberg.org/snonux/gos/internal/config"
+)
+
+func TestDecorate(t *testing.T) {
+	rules := []config.LinkRule{
+		{HTTPS: true},
+		{ExcludeDomains: []string{"foo.zone"}, StripTracking: true},
+		{Domains: []string{"foo.zone"}, Strip: []string{"draft"},
+			Query: map[string]string{"utm_source": "{platform}", "utm_medium": "social"}},
+		{Platforms: []string{"linkedin"}, Domains: []string{"old.example.org"}, Domain: "example.org"},
+		{Platforms: []string{"noop"}, Domains: []string{"foo.zone"}, Scheme: "gemini", Strip: []string{"utm_source", "utm_medium"}},
+	}
+	table := []struct {
+		platform string
+		content  string
+		expected string
+	}{
+		{"mastodon", "Read https://foo.zone/post.html!",
+			"Read https://foo.zone/post.html?utm_medium=social&utm_source=mastodon!"},
+		{"linkedin", "Read http://www.foo.zone/post.html?draft=1.",
+			"Read https://www.foo.zone/post.html?utm_medium=social&utm_source=linkedin."},
+		{"mastodon", "See https://news.example.org/a?id=1&utm_source=rss&fbclid=x",
+			"See https://news.example.org/a?id=1"},
+		{"linkedin", "See (https://old.example.org/a)", "See (https://example.org/a)"},
+		{"mastodon", "See https://old.example.org/a", "See https://old.example.org/a"},
+		{"mastodon", "No links at all", "No links at all"},
+		{"noop", "Read https://foo.zone/post.gmi", "Read gemini://foo.zone/post.gmi"},
+		{"mastodon", "See https://en.wikipedia.org/wiki/Go_(programming_language)?utm_source=x.",
+			"See https://en.wikipedia.org/wiki/Go_(programming_language)."},
+		{"mastodon", "(see https://en.wikipedia.org/wiki/Go_(programming_language)?utm_source=x)",
+			"(see https://en.wikipedia.org/wiki/Go_(programming_language))"},
+		{"mastodon", "See https://news.example.org/a?z=1&a=2&b=%2F", "See https://news.example.org/a?z=1&a=2&b=%2F"},
+		{"mastodon", "Read https://foo.zone/a?z=1&utm_source=rss&a=2",
+			"Read https://foo.zone/a?z=1&utm_source=mastodon&a=2&utm_medium=social"},
+	}
+	for _, tt := range table {
+		if got := Decorate(rules, tt.platform, tt.content); got != tt.expected {
+			t.Errorf("expected %q for %q on %s but got %q", tt.expected, tt.content, tt.platform, got)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	table := map[string]string{
+		"https://foo.zone/a?utm_source=mastodon&id=1":           "https://foo.zone/a?id=1",
+		"https://foo.zone/a?utm_source=mastodon":                "https://foo.zone/a",
+		"https://foo.zone/a":                                    "https://foo.zone/a",
+		"https://github.com/foo/bar/blob/main/README.md?ref=v1": "https://github.com/foo/bar/blob/main/README.md?ref=v1",
+		"https://twitter.com/foo?ref_src=twsrc%5Egoogle":        "https://twitter.com/foo",
+	}
+	for input, expected := range table {
+		if got := Clean(input); got != expected {
+			t.Errorf("expected %s for %s but got %s", expected, input, got)
+		}
+	}
+}
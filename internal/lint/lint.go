@@ -0,0 +1,342 @@
+// Package lint checks inboxed and queued entries for problems which otherwise
+// only surface at post time, e.g. over-limit content or broken links.
+package lint
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/dedup"
+	"codeberg.org/snonux/gos/internal/document"
+	"codeberg.org/snonux/gos/internal/entry"
+	"codeberg.org/snonux/gos/internal/hashtag"
+	"codeberg.org/snonux/gos/internal/mention"
+	"codeberg.org/snonux/gos/internal/platforms"
+	"codeberg.org/snonux/gos/internal/table"
+	"codeberg.org/snonux/gos/internal/tags"
+	"codeberg.org/snonux/gos/internal/textlen"
+)
+
+const linkTimeout = 10 * time.Second
+
+// Severity is the severity of an issue.
+type Severity int
+
+const (
+	// Warning is an issue which doesn't prevent posting, e.g. missing hashtags.
+	Warning Severity = iota
+	// Error is an issue which would make posting fail, e.g. over-limit content.
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	default:
+		panic(fmt.Sprintf("unknown severity: %d", int(s)))
+	}
+}
+
+// Issue is a single problem of an entry.
+type Issue struct {
+	Path     string
+	Platform string // Empty if not platform specific
+	Severity Severity
+	Message  string
+}
+
+// Report is the list of issues found.
+type Report []Issue
+
+// HasErrors returns true if any issue is an error.
+func (r Report) HasErrors() bool {
+	return slices.ContainsFunc(r, func(is Issue) bool { return is.Severity == Error })
+}
+
+// Render prints the report as a table.
+func (r Report) Render() error {
+	if len(r) == 0 {
+		colour.Infoln("No lint issues found")
+		return nil
+	}
+	tab := table.New().Header("Severity", "Platform", "Entry", "Problem")
+	for _, is := range r {
+		tab.Row(is.Severity.String(), is.Platform, is.Path, is.Message)
+	}
+	return tab.Render()
+}
+
+// Linter checks entries. It remembers the contents of all inboxed and queued
+// entries (to find duplicates) and the links already checked.
+type Linter struct {
+	args   config.Args
+	client *http.Client
+	links  map[string]Issue // Checked links and their issue, if any
+	fps    []dedup.Fingerprint
+	book   mention.AddressBook
+}
+
+// New returns a linter for the entries of the gosDir.
+func New(args config.Args) (*Linter, error) {
+	l := &Linter{
+		args:   args,
+		client: &http.Client{Timeout: linkTimeout},
+		links:  make(map[string]Issue),
+	}
+	var err error
+	if l.book, err = mention.Load(mention.Path(args)); err != nil {
+		return l, err
+	}
+	paths, err := l.entryPaths()
+	if err != nil {
+		return l, err
+	}
+	for _, path := range paths {
+		en, err := entry.New(path)
+		if err != nil {
+			continue
+		}
+		fp, err := dedup.New(en)
+		if err != nil {
+			continue
+		}
+		l.fps = append(l.fps, fp)
+	}
+	return l, nil
+}
+
+// All checks all inboxed and queued entries of the gosDir.
+func All(ctx context.Context, args config.Args) (Report, error) {
+	l, err := New(args)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := l.entryPaths()
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	for _, path := range paths {
+		report = append(report, l.Check(ctx, path)...)
+	}
+	return report, nil
+}
+
+// Check checks the entry against every platform it will be posted to.
+func (l *Linter) Check(ctx context.Context, filePath string) Report {
+	var report Report
+	add := func(platform string, severity Severity, format string, args ...any) {
+		report = append(report, Issue{filePath, platform, severity, fmt.Sprintf(format, args...)})
+	}
+
+	en, err := entry.New(filePath)
+	if err != nil {
+		add("", Error, "%v", err)
+		return report
+	}
+	share, shareErr := tags.NewShare(l.args, en.Tags)
+	if shareErr != nil {
+		add("", Error, "invalid share tag: %v", shareErr)
+	}
+	for platformStr := range en.FrontMatter.Platforms {
+		if _, err := platforms.New(platformStr); err != nil {
+			add("", Error, "invalid front matter platform: %v", err)
+		}
+	}
+
+	var urls []string
+	for _, platformStr := range l.platforms() {
+		platform, err := platforms.New(platformStr)
+		if err != nil {
+			add(platformStr, Error, "%v", err)
+			continue
+		}
+		if shareErr == nil && share.Excluded(platform.String()) {
+			continue
+		}
+		content, contentURLs, err := en.ForPlatform(platform.String()).ContentFor(platform.String(), l.args.Filters)
+		if err != nil {
+			add(platformStr, Error, "%v", err)
+			continue
+		}
+		if content == "" {
+			add(platformStr, Error, "empty content")
+			continue
+		}
+		sizeLimit := l.args.Platforms[platformStr]
+		if length := textlen.Count(platform.String(), content); length > sizeLimit {
+			add(platformStr, Error, "content too long (%d > %d)", length, sizeLimit)
+		}
+		count := hashtag.Count(content)
+		if count == 0 {
+			add(platformStr, Warning, "no hashtags")
+		}
+		if maxCount := l.args.Config.Hashtags.Max[platform.String()]; maxCount > 0 && count > maxCount {
+			add(platformStr, Warning, "too many hashtags (%d > %d)", count, maxCount)
+		}
+		for _, url := range contentURLs {
+			if !slices.Contains(urls, url) {
+				urls = append(urls, url)
+			}
+		}
+	}
+
+	for _, url := range urls {
+		if is, ok := l.checkLink(ctx, url); !ok {
+			is.Path = filePath
+			report = append(report, is)
+		}
+	}
+	report = append(report, l.checkMentions(en)...)
+	report = append(report, l.checkImages(en)...)
+	report = append(report, l.checkDocument(en)...)
+	report = append(report, l.checkDuplicates(en)...)
+	return report
+}
+
+func (l *Linter) checkLink(ctx context.Context, url string) (Issue, bool) {
+	if is, ok := l.links[url]; ok {
+		return is, is.Message == ""
+	}
+	is := Issue{}
+	status, err := l.linkStatus(ctx, url, http.MethodHead)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusForbidden ||
+		status == http.StatusNotImplemented) {
+		// Some servers don't support HEAD requests.
+		status, err = l.linkStatus(ctx, url, http.MethodGet)
+	}
+	switch {
+	case err != nil:
+		is = Issue{Severity: Warning, Message: fmt.Sprintf("unable to check link %s: %v", url, err)}
+	case status >= 400:
+		is = Issue{Severity: Error, Message: fmt.Sprintf("broken link %s (%d %s)", url, status, http.StatusText(status))}
+	}
+	l.links[url] = is
+	return is, is.Message == ""
+}
+
+func (l *Linter) linkStatus(ctx context.Context, url, method string) (int, error) {
+	newCtx, cancel := context.WithTimeout(ctx, linkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(newCtx, method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			colour.Errorln("Error closing response body:", err)
+		}
+	}()
+	return resp.StatusCode, nil
+}
+
+func (l *Linter) checkMentions(en entry.Entry) Report {
+	content, _, err := en.Content()
+	if err != nil {
+		return nil
+	}
+	var report Report
+	for _, key := range l.book.Unknown(content) {
+		report = append(report, Issue{en.Path, "", Warning, fmt.Sprintf("unknown mention @{%s}, not in the address book", key)})
+	}
+	return report
+}
+
+func (l *Linter) checkImages(en entry.Entry) Report {
+	var report Report
+	images, err := en.Images()
+	if err != nil {
+		return Report{{en.Path, "", Error, err.Error()}}
+	}
+	for _, image := range images {
+		if strings.Contains(image.Path, "://") {
+			continue
+		}
+		imagePath := image.Path
+		if !filepath.IsAbs(imagePath) {
+			imagePath = filepath.Join(l.args.GosDir, image.Path)
+		}
+		if _, err := os.Stat(imagePath); err != nil {
+			report = append(report, Issue{en.Path, "", Error, fmt.Sprintf("missing image %s", image.Path)})
+		}
+	}
+	return report
+}
+
+// checkDocument reports a document (PDF) which is missing, or which exceeds the
+// size or page limits of LinkedIn. A page count which can't be determined, e.g.
+// due to compressed object streams, is a warning.
+func (l *Linter) checkDocument(en entry.Entry) Report {
+	doc := en.FrontMatter.Document
+	if doc.Path == "" {
+		return nil
+	}
+	_, ok, err := document.Check(document.Resolve(l.args.GosDir, doc.Path))
+	switch {
+	case err != nil:
+		return Report{{en.Path, "", Error, fmt.Sprintf("invalid document: %v", err)}}
+	case !ok:
+		return Report{{en.Path, "", Warning, fmt.Sprintf("page count of document %s unknown, LinkedIn allows at most %d pages", doc.Path, document.MaxPages)}}
+	}
+	return nil
+}
+
+// checkDuplicates reports other entries with the same or a similar content, or
+// with the same links. Copies of the same entry (e.g. in the inbox and in the
+// platform queues) are not reported.
+func (l *Linter) checkDuplicates(en entry.Entry) Report {
+	fp, err := dedup.New(en)
+	if err != nil {
+		return nil
+	}
+	var report Report
+	for _, otherFp := range l.fps {
+		m := fp.Compare(otherFp)
+		if !m.Duplicate(l.args.DuplicateThreshold, l.args.DuplicateLinkThreshold) {
+			continue
+		}
+		other, err := entry.New(otherFp.Path)
+		if err != nil || dedup.SameEntry(en, other) {
+			continue
+		}
+		report = append(report, Issue{en.Path, "", Warning, "possible duplicate of " + m.String()})
+	}
+	return report
+}
+
+// entryPaths returns the paths of all inboxed and queued entries.
+func (l *Linter) entryPaths() ([]string, error) {
+	var paths []string
+	for _, pattern := range []string{"*.txt", "*.md", "db/*.queued", "db/platforms/*/*.queued"} {
+		matches, err := filepath.Glob(filepath.Join(l.args.GosDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+func (l *Linter) platforms() []string {
+	platformStrs := make([]string, 0, len(l.args.Platforms))
+	for platformStr := range l.args.Platforms {
+		platformStrs = append(platformStrs, platformStr)
+	}
+	slices.Sort(platformStrs)
+	return platformStrs
+}
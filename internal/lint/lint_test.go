@@ -0,0 +1,83 @@
+package lint
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"codeberg.org/snonux/gos/internal/config"
+)
+
+func TestCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	gosDir := t.TempDir()
+	files := map[string]string{
+		"ok.share:ma.txt":                    "Hello world #foo " + server.URL + "/ok",
+		"broken.share:ma.txt":                "A broken link #foo " + server.URL + "/broken",
+		"nohashtags.txt":                     "Hello world",
+		"toolong.txt":                        "Hello world, this is a way too long message #foo",
+		"badshare.share:foo.txt":             "Bad share tag #foo",
+		"image.md":                           "An image #foo\n\n![cat](./cat.jpg)",
+		"slides.txt":                         "---\ndocument:\n  path: ./slides.pdf\n---\nMy slides #foo",
+		"objstm.txt":                         "---\ndocument:\n  path: ./objstm.pdf\n---\nMy slides #foo",
+		"objstm.pdf":                         "%PDF-1.5\n1 0 obj\n<< /Type /ObjStm /N 5 >>\nstream\nendstream\nendobj\n",
+		"dup1.txt":                           "Same content #foo",
+		"db/dup2.txt.20250101-010101.queued": "same   CONTENT #foo",
+		"db/platforms/mastodon/dup1.txt.20250101-010101.queued": "Same content #foo",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(gosDir, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	args := config.Args{GosDir: gosDir, Platforms: map[string]int{"Mastodon": 500, "LinkedIn": 40}}
+	report, err := All(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := make(map[string][]string)
+	for _, is := range report {
+		name := filepath.Base(is.Path)
+		issues[name] = append(issues[name], is.Severity.String()+": "+is.Message)
+	}
+	expected := map[string]string{
+		"ok.share:ma.txt":                 "",
+		"broken.share:ma.txt":             "error: broken link",
+		"nohashtags.txt":                  "warning: no hashtags",
+		"toolong.txt":                     "error: content too long",
+		"badshare.share:foo.txt":          "error: invalid share tag",
+		"image.md":                        "error: missing image ./cat.jpg",
+		"slides.txt":                      "error: invalid document",
+		"objstm.txt":                      "warning: page count of document ./objstm.pdf unknown",
+		"dup1.txt":                        "warning: possible duplicate of queued 2025-01-01 01:01:01 " + filepath.Join(gosDir, "db/dup2.txt.20250101-010101.queued"),
+		"dup2.txt.20250101-010101.queued": "warning: possible duplicate of inboxed " + filepath.Join(gosDir, "dup1.txt"),
+	}
+	for name, prefix := range expected {
+		got := strings.Join(issues[name], "; ")
+		if prefix == "" && got != "" {
+			t.Errorf("expected no issues for %s but got '%s'", name, got)
+		}
+		if prefix != "" && !strings.Contains(got, prefix) {
+			t.Errorf("expected '%s' for %s but got '%s'", prefix, name, got)
+		}
+	}
+	if !report.HasErrors() {
+		t.Error("expected the report to have errors")
+	}
+}
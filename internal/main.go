@@ -10,7 +10,13 @@ import (
 	"time"
 
 	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/dedup"
+	"codeberg.org/snonux/gos/internal/hashtag"
+	"codeberg.org/snonux/gos/internal/journal"
+	"codeberg.org/snonux/gos/internal/link"
+	"codeberg.org/snonux/gos/internal/mention"
 	"codeberg.org/snonux/gos/internal/schedule"
+	"codeberg.org/snonux/gos/internal/secrets"
 )
 
 // Main is the entry point for the Gos application.
@@ -22,8 +28,11 @@ func Main(composeModeDefault bool) {
 	version := flag.Bool("version", false, "Display version")
 	composeMode := flag.Bool("compose", composeModeDefault, "Compose a new entry")
 	gosDir := flag.String("gosDir", filepath.Join(os.Getenv("HOME"), ".gosdir"), "Gos' queue and DB directory")
-	// cacheDir := flag.String("cacheDir", filepath.Join(*gosDir, "cache"), "Go's cache dir")
+	cacheDir := flag.String("cacheDir", "", "Gos' cache dir (default is the cache sub dir of the gosDir)")
+	previewTTL := flag.Int("previewTTL", 7, "How many days to cache link previews")
 	browser := flag.String("browser", "firefox", "OAuth2 browser")
+	headless := flag.Bool("headless", false, "OAuth2 without a local browser, e.g. over SSH: print the URL and paste the callback URL back")
+	secretsKeyFile := flag.String("secretsKeyFile", "", "Key file unlocking the encrypted credential store, instead of a passphrase")
 	configPath := flag.String("configPath", filepath.Join(os.Getenv("HOME"), ".config/gos/gos.json"), "Gos' config file path")
 	platforms := flag.String("platforms", "Mastodon:500,LinkedIn:1000,Noop:2000", "Platforms enabled plus their post size limits")
 	target := flag.Int("target", 2, "How many posts per week are the target?")
@@ -32,6 +41,16 @@ func Main(composeModeDefault bool) {
 	pauseDays := flag.Int("pauseDays", 2, "How many days until next post can be posted?")
 	runInterval := flag.Int("runInterval", 6, "How many hours to wait for the next run.")
 	lookback := flag.Int("lookback", 42, "How many days look back in time for posting history")
+	trashRetention := flag.Int("trashRetention", 180, "How many days to keep entries in the trashbin (0 keeps them forever)")
+	lintBlock := flag.Bool("lintBlock", false, "Keep entries with lint errors in the inbox instead of queueing them")
+	duplicateWindow := flag.Int("duplicateWindow", 365, "How many days to look back for duplicates of new entries (0 looks back forever)")
+	duplicateThreshold := flag.Float64("duplicateThreshold", dedup.DefaultThreshold, "Text similarity (0-1) from which on new entries are near duplicates")
+	duplicateLinkThreshold := flag.Float64("duplicateLinkThreshold", dedup.DefaultLinkThreshold, "Text similarity (0-1) from which on new entries sharing a link are duplicates")
+	duplicateRefuse := flag.Bool("duplicateRefuse", false, "Keep duplicates in the inbox instead of asking whether to queue them")
+	evergreenMinAge := flag.Int("evergreenMinAge", 365, "Minimum days since the last post until an evergreen entry is recycled")
+	evergreenMaxReposts := flag.Int("evergreenMaxReposts", 3, "Maximum number of times an evergreen entry is reposted")
+	evergreenShare := flag.Float64("evergreenShare", 0.25, "Share (0-1) of the weekly target recycled evergreen entries may use")
+	seriesReply := flag.Bool("seriesReply", false, "Post series parts as replies to their previous part (Mastodon only)")
 	geminiSummaryFor := flag.String("geminiSummaryFor", "", "Generate a summary in Gemini Gemtext format, format is coma separated string of months, e.g. 202410,202411")
 	geminiCapsules := flag.String("geminiCapsules", "foo.zone", "Comma separated list Gemini capsules. Used by geminiEnable to detect Gemtext links")
 	gemtexterEnable := flag.Bool("gemtexterEnable", false, "Add special Gemtexter (the static site generator) tags to the Gemini Gemtext summary")
@@ -47,37 +66,64 @@ func Main(composeModeDefault bool) {
 
 	// Create args from parsed flags
 	args := config.Args{
-		DryRun:          *dry,
-		GosDir:          *gosDir,
-		Target:          *target,
-		MinQueued:       *minQueued,
-		MaxDaysQueued:   *maxDaysQueued,
-		PauseDays:       *pauseDays,
-		RunInterval:     time.Duration(*runInterval) * time.Hour, // TODO: Document
-		Lookback:        time.Duration(*lookback) * time.Hour * 24,
-		ConfigPath:      *configPath,
-		OAuth2Browser:   *browser,
-		GemtexterEnable: *gemtexterEnable,
-		GeminiCapsules:  strings.Split(*geminiCapsules, ","),
-		ComposeMode:     *composeMode,
-		StatsOnly:       *statsOnly,
+		DryRun:                 *dry,
+		GosDir:                 *gosDir,
+		CacheDir:               *cacheDir,
+		PreviewTTL:             time.Duration(*previewTTL) * time.Hour * 24,
+		Target:                 *target,
+		MinQueued:              *minQueued,
+		MaxDaysQueued:          *maxDaysQueued,
+		PauseDays:              *pauseDays,
+		RunInterval:            time.Duration(*runInterval) * time.Hour, // TODO: Document
+		Lookback:               time.Duration(*lookback) * time.Hour * 24,
+		TrashRetention:         time.Duration(*trashRetention) * time.Hour * 24,
+		LintBlock:              *lintBlock,
+		DuplicateWindow:        time.Duration(*duplicateWindow) * time.Hour * 24,
+		DuplicateThreshold:     *duplicateThreshold,
+		DuplicateLinkThreshold: *duplicateLinkThreshold,
+		DuplicateRefuse:        *duplicateRefuse,
+		EvergreenMinAge:        time.Duration(*evergreenMinAge) * time.Hour * 24,
+		EvergreenMaxReposts:    *evergreenMaxReposts,
+		EvergreenShare:         *evergreenShare,
+		SeriesReply:            *seriesReply,
+		ConfigPath:             *configPath,
+		OAuth2Browser:          *browser,
+		OAuth2Headless:         *headless,
+		GemtexterEnable:        *gemtexterEnable,
+		GeminiCapsules:         strings.Split(*geminiCapsules, ","),
+		ComposeMode:            *composeMode,
+		StatsOnly:              *statsOnly,
+	}
+	if args.CacheDir == "" {
+		args.CacheDir = filepath.Join(args.GosDir, "cache")
 	}
 	if *geminiSummaryFor != "" {
 		args.GeminiSummaryFor = strings.Split(*geminiSummaryFor, ",")
 	}
 
-	// Load configuration
+	// Load configuration. The encrypted credential store (if any) is unlocked with
+	// the key file or the passphrase, but only once the secrets are needed.
+	config.Passphrase = secrets.Passphrase(*secretsKeyFile)
 	conf, err := config.New(args.ConfigPath, args.ComposeMode)
 	if err != nil {
 		log.Fatal(err)
 	}
 	args.Config = conf
 
+	// Record all filesystem transitions, so that they can be undone.
+	if !args.DryRun {
+		journal.Enable(args.GosDir)
+	}
+
 	// Parse platforms
 	if err := args.ParsePlatforms(*platforms); err != nil {
 		log.Fatal(err)
 	}
 
+	// Expand the mentions, decorate the links, and append, rewrite and limit the
+	// hashtags of the content posted.
+	args.Filters = []config.ContentFilter{mention.Filter(args), link.Filter(args), hashtag.Filter(args)}
+
 	// Handle stats only flag
 	if args.StatsOnly {
 		// Call the new function to print all stats
@@ -88,6 +134,14 @@ func Main(composeModeDefault bool) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Handle sub-commands, e.g. "gos requeue foo.txt"
+	if flag.NArg() > 0 {
+		if err := runCommand(ctx, args, flag.Args()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if err := run(ctx, args); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,194 @@
+// Package markdown renders Markdown entries (e.g. written with Obsidian) into
+// plain text suitable for social media platforms.
+package markdown
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	fencedCodeRE  = regexp.MustCompile("^\\s*(```|~~~)")
+	headingRE     = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*\s*$`)
+	ruleRE        = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+	quoteRE       = regexp.MustCompile(`^\s*>\s?`)
+	bulletRE      = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	imageRE       = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	linkRE        = regexp.MustCompile(`\[([^\]]+)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	autolinkRE    = regexp.MustCompile(`<((?:https?|ftp)://[^>\s]+)>`)
+	wikilinkRE    = regexp.MustCompile(`\[\[([^\]|]+)(?:\|([^\]]+))?\]\]`)
+	mentionRE     = regexp.MustCompile(`@\[((?:[^\[\]\\]|\\.)+)\]\((urn:li:(?:person|organization):[^()\s]+)\)`)
+	bareURLRE     = regexp.MustCompile(`(?:https?|ftp)://\S+`)
+	inlineCodeRE  = regexp.MustCompile("`([^`]+)`")
+	strongRE      = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	emphasisRE    = regexp.MustCompile(`(^|[^\w*])[*_](\S(?:[^*_]*?\S)?)[*_]([^\w*]|$)`)
+	strikeRE      = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	escapeRE      = regexp.MustCompile(`\\([\\` + "`" + `*_{}\[\]()#+\-.!~|>])`)
+	placeholderRE = regexp.MustCompile("\x00(\\d+)\x00")
+	blankLinesRE  = regexp.MustCompile(`\n{3,}`)
+	// Marks a dropped image, so that the spaces around it can be dropped too.
+	droppedStartRE = regexp.MustCompile("^[ \t]*\x01[ \t]*")
+	droppedRE      = regexp.MustCompile("[ \t]*\x01")
+)
+
+// IsMarkdown returns true if the file (or entry name) has a Markdown extension.
+func IsMarkdown(name string) bool {
+	return strings.HasSuffix(name, ".md")
+}
+
+// SplitFrontMatter splits a leading front matter block (delimited by --- for YAML
+// or +++ for TOML) from the content. It returns the front matter without its
+// delimiters, its delimiter, and the remaining content.
+func SplitFrontMatter(content string) (string, string, string) {
+	for _, delim := range []string{"---", "+++"} {
+		if !strings.HasPrefix(content, delim+"\n") && !strings.HasPrefix(content, delim+"\r\n") {
+			continue
+		}
+		rest := content[strings.Index(content, "\n")+1:]
+		lines := strings.SplitAfter(rest, "\n")
+		offset := 0
+		for _, line := range lines {
+			if strings.TrimRight(line, "\r\n") == delim {
+				return rest[:offset], delim, strings.TrimSpace(rest[offset+len(line):])
+			}
+			offset += len(line)
+		}
+	}
+	return "", "", content
+}
+
+// Image is an image referenced in the content.
+type Image struct {
+	Path string // The path or URL
+	Alt  string // The alt text, if any
+}
+
+// Images returns all images referenced in the content.
+func Images(content string) []Image {
+	var images []Image
+	for _, sub := range imageRE.FindAllStringSubmatch(content, -1) {
+		images = append(images, Image{Path: sub[2], Alt: strings.TrimSpace(sub[1])})
+	}
+	return images
+}
+
+// Render renders Markdown into the text posted to the platform, e.g. "mastodon".
+// Markup is removed, and links are turned into their text followed by the bare
+// URL, so that the platforms can link them by themselves. Mentions in the LinkedIn
+// little text format, e.g. @[Paul](urn:li:person:123), are kept for LinkedIn (and
+// its organisation pages), which escapes everything else of the little text format
+// when posting. For all other platforms, only the name is kept. Images are attached
+// to LinkedIn posts natively, so they are dropped from its text. The other platforms
+// get the alt text and the URL of remote images, but only the alt text of local
+// ones, as their paths mean nothing to the readers.
+func Render(platform, content string) string {
+	linkedIn := strings.HasPrefix(strings.ToLower(platform), "linkedin")
+	var (
+		sb     strings.Builder
+		inCode bool
+	)
+	for i, line := range strings.Split(content, "\n") {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if fencedCodeRE.MatchString(line) {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			sb.WriteString(line)
+			continue
+		}
+		sb.WriteString(renderLine(line, linkedIn))
+	}
+	return strings.TrimSpace(blankLinesRE.ReplaceAllString(sb.String(), "\n\n"))
+}
+
+func renderLine(line string, linkedIn bool) string {
+	if ruleRE.MatchString(line) {
+		return ""
+	}
+	line = quoteRE.ReplaceAllString(line, "")
+	line = headingRE.ReplaceAllString(line, "$1")
+	line = bulletRE.ReplaceAllString(line, "$1- ")
+
+	// Protect URLs and inline code from the emphasis rules, e.g. for underscores
+	// in URLs. The protected parts are restored afterwards.
+	var protected []string
+	protect := func(s string) string {
+		protected = append(protected, s)
+		return fmt.Sprintf("\x00%d\x00", len(protected)-1)
+	}
+
+	line = inlineCodeRE.ReplaceAllStringFunc(line, func(m string) string {
+		return protect(inlineCodeRE.FindStringSubmatch(m)[1])
+	})
+	// Before the escapes, as the names of mentions are escaped.
+	line = mentionRE.ReplaceAllStringFunc(line, func(m string) string {
+		if linkedIn {
+			return protect(m)
+		}
+		return protect(escapeRE.ReplaceAllString(mentionRE.FindStringSubmatch(m)[1], "$1"))
+	})
+	line = escapeRE.ReplaceAllStringFunc(line, func(m string) string {
+		return protect(m[1:])
+	})
+	line = imageRE.ReplaceAllStringFunc(line, func(m string) string {
+		sub := imageRE.FindStringSubmatch(m)
+		switch alt := strings.TrimSpace(sub[1]); {
+		case linkedIn, alt == "" && !IsRemote(sub[2]):
+			return "\x01"
+		case !IsRemote(sub[2]):
+			return alt
+		default:
+			return linkText(alt, protect(sub[2]))
+		}
+	})
+	line = droppedStartRE.ReplaceAllString(line, "")
+	line = droppedRE.ReplaceAllString(line, "")
+	line = linkRE.ReplaceAllStringFunc(line, func(m string) string {
+		sub := linkRE.FindStringSubmatch(m)
+		if sub[1] == sub[2] {
+			return protect(sub[2])
+		}
+		return linkText(sub[1], protect(sub[2]))
+	})
+	line = autolinkRE.ReplaceAllStringFunc(line, func(m string) string {
+		return protect(autolinkRE.FindStringSubmatch(m)[1])
+	})
+	line = bareURLRE.ReplaceAllStringFunc(line, protect)
+	line = wikilinkRE.ReplaceAllStringFunc(line, func(m string) string {
+		sub := wikilinkRE.FindStringSubmatch(m)
+		if sub[2] != "" {
+			return sub[2]
+		}
+		return sub[1]
+	})
+
+	line = strongRE.ReplaceAllString(line, "$2")
+	// Adjacent emphasis shares the separating character, so repeat until stable.
+	for prev := ""; prev != line; {
+		prev = line
+		line = emphasisRE.ReplaceAllString(line, "$1$2$3")
+	}
+	line = strikeRE.ReplaceAllString(line, "$1")
+
+	return placeholderRE.ReplaceAllStringFunc(line, func(m string) string {
+		var i int
+		_, _ = fmt.Sscanf(strings.Trim(m, "\x00"), "%d", &i)
+		return protected[i]
+	})
+}
+
+// IsRemote returns true if the image path is a URL and not a local path.
+func IsRemote(path string) bool {
+	return strings.Contains(path, "://")
+}
+
+func linkText(text, url string) string {
+	if text = strings.TrimSpace(text); text == "" {
+		return url
+	}
+	return text + " " + url
+}
@@ -0,0 +1,79 @@
+package markdown
+
+import (
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	table := map[string]string{
+		"Hello **bold** and *italic* and _also_ __this__": "Hello bold and italic and also this",
+		"*a* *b*":                    "a b",
+		"# Heading\n\nText #hashtag": "Heading\n\nText #hashtag",
+		"Read [my post](https://foo.zone/a_b_c.html)!":  "Read my post https://foo.zone/a_b_c.html!",
+		"[https://foo.zone](https://foo.zone)":          "https://foo.zone",
+		"Bare https://foo.zone/snake_case_url here":     "Bare https://foo.zone/snake_case_url here",
+		"Auto <https://foo.zone>":                       "Auto https://foo.zone",
+		"![A cat](https://foo.zone/cat.jpg)":            "A cat https://foo.zone/cat.jpg",
+		"Look at my cat ![A cat](./cat.jpg) #cats":      "Look at my cat A cat #cats",
+		"Look at my cat ![](./cat.jpg) #cats":           "Look at my cat #cats",
+		"![](./cat.jpg)\n\nMy cat":                      "My cat",
+		"- one\n* two\n+ three":                         "- one\n- two\n- three",
+		"> quoted":                                      "quoted",
+		"a\n\n---\n\nb":                                 "a\n\nb",
+		"`**code**` and ~~gone~~":                       "**code** and gone",
+		"snake_case_word and 2 * 3 * 4":                 "snake_case_word and 2 * 3 * 4",
+		"See [[Some Page]] and [[Other|alias]]":         "See Some Page and alias",
+		"Escaped \\*stars\\*":                           "Escaped *stars*",
+		"```\n**kept**\n```":                            "**kept**",
+		"Thanks @[Paul](urn:li:person:123)!":            "Thanks Paul!",
+		`Thanks @[Acme \(EU\)](urn:li:organization:42)`: "Thanks Acme (EU)",
+	}
+	for input, expected := range table {
+		t.Run(input, func(t *testing.T) {
+			if rendered := Render("mastodon", input); rendered != expected {
+				t.Errorf("expected '%s' but got '%s'", expected, rendered)
+			}
+		})
+	}
+}
+
+func TestRenderLinkedIn(t *testing.T) {
+	table := map[string]string{
+		"Hello **bold** and *italic*":                            "Hello bold and italic",
+		"Read [my post](https://foo.zone/a_b_c.html)!":           "Read my post https://foo.zone/a_b_c.html!",
+		"Thanks @[Paul](urn:li:person:123)!":                     "Thanks @[Paul](urn:li:person:123)!",
+		"**Thanks** @[Acme Inc](urn:li:organization:42) (again)": "Thanks @[Acme Inc](urn:li:organization:42) (again)",
+		`Thanks @[Acme \(EU\)](urn:li:organization:42)`:          `Thanks @[Acme \(EU\)](urn:li:organization:42)`,
+		"[Paul](https://foo.zone/paul)":                          "Paul https://foo.zone/paul",
+		"- one\n* two":                                           "- one\n- two",
+		"Look at my cat ![A cat](./cat.jpg) #cats":               "Look at my cat #cats",
+		"![A cat](https://foo.zone/cat.jpg) My cat":              "My cat",
+	}
+	for _, platform := range []string{"linkedin", "linkedinorg-acme"} {
+		for input, expected := range table {
+			t.Run(platform+" "+input, func(t *testing.T) {
+				if rendered := Render(platform, input); rendered != expected {
+					t.Errorf("expected '%s' but got '%s'", expected, rendered)
+				}
+			})
+		}
+	}
+}
+
+func TestSplitFrontMatter(t *testing.T) {
+	table := []struct {
+		input, frontMatter, delim, content string
+	}{
+		{"---\ntags: [a]\n---\nHello", "tags: [a]\n", "---", "Hello"},
+		{"+++\ntags = [\"a\"]\n+++\n\nHello", "tags = [\"a\"]\n", "+++", "Hello"},
+		{"Hello\n---\nWorld", "", "", "Hello\n---\nWorld"},
+		{"---\nunterminated", "", "", "---\nunterminated"},
+	}
+	for _, tt := range table {
+		frontMatter, delim, content := SplitFrontMatter(tt.input)
+		if frontMatter != tt.frontMatter || delim != tt.delim || content != tt.content {
+			t.Errorf("expected (%q, %q, %q) but got (%q, %q, %q) for %q",
+				tt.frontMatter, tt.delim, tt.content, frontMatter, delim, content, tt.input)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package mention expands the platform neutral mentions of entries, e.g. @{alice},
+// into the native mention of each platform, using the address book.
+package mention
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/textlen"
+)
+
+// The name of the address book file in the config directory.
+const addressBookFile = "addressbook.json"
+
+var mentionRE = regexp.MustCompile(`@\{([\w.-]+)\}`)
+
+// Contact is a person (or organisation) of the address book.
+type Contact struct {
+	// The name used when there is no handle for the platform.
+	Name string
+	// The handles per lower case platform, e.g. "@alice@fosstodon.org" for
+	// mastodon and "urn:li:person:..." for linkedin.
+	Handles map[string]string
+}
+
+// AddressBook maps the neutral mention keys to the contacts, e.g. "alice".
+type AddressBook map[string]Contact
+
+// Path returns the path of the address book, which is in the config directory.
+func Path(args config.Args) string {
+	return filepath.Join(filepath.Dir(args.ConfigPath), addressBookFile)
+}
+
+// Load reads the address book. A missing address book is empty.
+func Load(path string) (AddressBook, error) {
+	book := make(AddressBook)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return book, nil
+	}
+	if err != nil {
+		return book, err
+	}
+	if err := json.Unmarshal(data, &book); err != nil {
+		return book, fmt.Errorf("invalid address book %s: %w", path, err)
+	}
+	return book, nil
+}
+
+// Filter returns the content filter which expands the mentions. The address book
+// is loaded once, on first use.
+func Filter(args config.Args) config.ContentFilter {
+	var (
+		once sync.Once
+		book AddressBook
+	)
+	return func(tags []string, platform, content string) string {
+		once.Do(func() {
+			var err error
+			if book, err = Load(Path(args)); err != nil {
+				colour.Errorln(err)
+			}
+		})
+		return book.Expand(platform, content)
+	}
+}
+
+// Expand replaces the neutral mentions of the content with the native mentions
+// of the platform. Without a handle for the platform, the plain name is used.
+func (book AddressBook) Expand(platform, content string) string {
+	return mentionRE.ReplaceAllStringFunc(content, func(match string) string {
+		key := mentionRE.FindStringSubmatch(match)[1]
+		contact, ok := book[key]
+		if !ok {
+			return key
+		}
+		name := contact.Name
+		if name == "" {
+			name = key
+		}
+		handle, ok := contact.Handles[platform]
+		if !ok {
+			// E.g. the linkedin handle for a LinkedIn organisation page.
+			handle = contact.Handles[config.Network(platform)]
+		}
+		switch {
+		case handle == "":
+			return name
+		case config.Network(platform) == "linkedin":
+			// The little text format, which LinkedIn turns into a link to the member.
+			// The name is escaped, as e.g. a ")" would end the mention.
+			return fmt.Sprintf("@[%s](%s)", textlen.EscapeLinkedIn(name), handle)
+		case !strings.HasPrefix(handle, "@"):
+			return "@" + handle
+		default:
+			return handle
+		}
+	})
+}
+
+// Unknown returns the mention keys of the content which aren't in the address book.
+func (book AddressBook) Unknown(content string) []string {
+	var unknown []string
+	for _, m := range mentionRE.FindAllStringSubmatch(content, -1) {
+		if _, ok := book[m[1]]; !ok {
+			unknown = append(unknown, m[1])
+		}
+	}
+	return unknown
+}
@@ -0,0 +1,69 @@
+package mention
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	book := AddressBook{
+		"alice": {Name: "Alice Doe", Handles: map[string]string{
+			"mastodon": "@alice@fosstodon.org",
+			"linkedin": "urn:li:person:a1B2",
+			"bluesky":  "alice.bsky.social",
+		}},
+		"bob":  {Handles: map[string]string{"mastodon": "@bob@foo.zone"}},
+		"acme": {Name: "Acme (Europe) [Ltd]", Handles: map[string]string{"linkedin": "urn:li:organization:42"}},
+	}
+	table := []struct {
+		platform string
+		content  string
+		expected string
+	}{
+		{"mastodon", "Thanks @{alice}!", "Thanks @alice@fosstodon.org!"},
+		{"linkedin", "Thanks @{alice}!", "Thanks @[Alice Doe](urn:li:person:a1B2)!"},
+		{"bluesky", "Thanks @{alice}!", "Thanks @alice.bsky.social!"},
+		{"noop", "Thanks @{alice}!", "Thanks Alice Doe!"},
+		{"linkedin", "Thanks @{bob}", "Thanks bob"},
+		{"linkedin", "Thanks @{acme}", `Thanks @[Acme \(Europe\) \[Ltd\]](urn:li:organization:42)`},
+		{"mastodon", "Thanks @{bob} and @{carol}", "Thanks @bob@foo.zone and carol"},
+		{"mastodon", "Mail alice@foo.zone", "Mail alice@foo.zone"},
+	}
+	for _, tt := range table {
+		if got := book.Expand(tt.platform, tt.content); got != tt.expected {
+			t.Errorf("expected %q for %q on %s but got %q", tt.expected, tt.content, tt.platform, got)
+		}
+	}
+
+	if unknown := book.Unknown("@{alice} @{carol} @{dave}"); !slices.Equal(unknown, []string{"carol", "dave"}) {
+		t.Errorf("expected unknown mentions carol and dave but got %v", unknown)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), addressBookFile)
+	book, err := Load(path)
+	if err != nil || len(book) != 0 {
+		t.Fatalf("expected an empty address book without error but got %v, %v", book, err)
+	}
+
+	data := `{"alice": {"Name": "Alice Doe", "Handles": {"mastodon": "@alice@fosstodon.org"}}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if book, err = Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if book["alice"].Handles["mastodon"] != "@alice@fosstodon.org" {
+		t.Errorf("unexpected address book %v", book)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected an error for an invalid address book")
+	}
+}
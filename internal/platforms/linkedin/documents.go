@@ -0,0 +1,34 @@
+package linkedin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/document"
+	"codeberg.org/snonux/gos/internal/entry"
+)
+
+// The time to upload and process a document, LinkedIn converts each page.
+const documentTimeout = 2 * time.Minute
+
+// Uploads the document and returns the content of a document post.
+// https://learn.microsoft.com/en-us/linkedin/marketing/community-management/shares/documents-api
+func postDocumentToLinkedInAPI(ctx context.Context, ownerURN, accessToken, gosDir string, doc entry.Document, liVersion string) (map[string]interface{}, error) {
+	filePath := document.Resolve(gosDir, doc.Path)
+	// The limits are checked when queueing, but the document may have changed since.
+	if _, _, err := document.Check(filePath); err != nil {
+		return nil, err
+	}
+	colour.Infoln("Uploading document", filePath, "to LinkedIn")
+	documentURN, err := uploadMedia(ctx, documentsAPI, ownerURN, accessToken, filePath, liVersion)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	title := doc.Title
+	if title == "" {
+		title = document.Title(filePath)
+	}
+	return map[string]interface{}{"media": map[string]interface{}{"id": documentURN, "title": title}}, nil
+}
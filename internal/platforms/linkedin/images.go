@@ -0,0 +1,73 @@
+package linkedin
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/entry"
+	"codeberg.org/snonux/gos/internal/markdown"
+	"codeberg.org/snonux/gos/internal/preview"
+)
+
+const (
+	// LinkedIn allows up to 20 images in a multi image post.
+	maxImages = 20
+	// The time to upload and process each image of a post.
+	imageTimeout = 30 * time.Second
+)
+
+// Returns the images attached to the entry, with the local paths resolved. Images
+// linked by URL are downloaded into the cache directory first.
+func entryImages(ctx context.Context, args config.Args, en entry.Entry) ([]markdown.Image, error) {
+	images, err := en.Images()
+	if err != nil {
+		return nil, err
+	}
+	if len(images) > maxImages {
+		return nil, fmt.Errorf("%s has got %d images, but LinkedIn allows at most %d", en.Path, len(images), maxImages)
+	}
+	for i, image := range images {
+		switch {
+		case markdown.IsRemote(image.Path):
+			newCtx, cancel := context.WithTimeout(ctx, imageTimeout)
+			images[i].Path, err = preview.DownloadImage(newCtx, args, image.Path)
+			cancel()
+			if err != nil {
+				return nil, fmt.Errorf("unable to download image %s: %w", image.Path, err)
+			}
+		case !filepath.IsAbs(image.Path):
+			images[i].Path = filepath.Join(args.GosDir, image.Path)
+		}
+	}
+	return images, nil
+}
+
+// Uploads the images and returns the content of an image post: a single image
+// (media) or several ones (multiImage), with their alt texts.
+func postImagesToLinkedInAPI(ctx context.Context, ownerURN, accessToken string, images []markdown.Image, liVersion string) (map[string]interface{}, error) {
+	var media []map[string]interface{}
+	for _, image := range images {
+		colour.Infoln("Uploading image", image.Path, "to LinkedIn")
+		imageURN, err := postImageToLinkedInAPI(ctx, ownerURN, accessToken, image.Path, liVersion)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", image.Path, err)
+		}
+		m := map[string]interface{}{"id": imageURN}
+		if image.Alt != "" {
+			m["altText"] = image.Alt
+		}
+		media = append(media, m)
+	}
+	return imageContent(media), nil
+}
+
+func imageContent(media []map[string]interface{}) map[string]interface{} {
+	if len(media) == 1 {
+		return map[string]interface{}{"media": media[0]}
+	}
+	return map[string]interface{}{"multiImage": map[string]interface{}{"images": media}}
+}
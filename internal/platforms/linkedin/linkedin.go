@@ -9,48 +9,88 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"codeberg.org/snonux/gos/internal/colour"
 	"codeberg.org/snonux/gos/internal/config"
 	"codeberg.org/snonux/gos/internal/entry"
+	"codeberg.org/snonux/gos/internal/markdown"
 	"codeberg.org/snonux/gos/internal/platforms/linkedin/oauth2"
+	"codeberg.org/snonux/gos/internal/preview"
 	"codeberg.org/snonux/gos/internal/prompt"
+	"codeberg.org/snonux/gos/internal/textlen"
 )
 
 var errUnauthorized = errors.New("unauthorized access, refresh or create token?")
 
 const linkedInTimeout = 10 * time.Second
 
+// The LinkedIn-Version used when none is configured.
+const defaultVersion = "202601"
+
 // addCommonHeaders applies required headers and optional LinkedIn versioning.
 func addCommonHeaders(req *http.Request, accessToken, liVersion string) {
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-RestLi-Protocol-Version", "2.0.0")
 	if liVersion == "" {
-		liVersion = "202601" // Default to latest stable version
+		liVersion = defaultVersion // Default to latest stable version
 	}
 	req.Header.Set("LinkedIn-Version", liVersion)
 }
 
+// target is who posts the entry: the member, or one of the organisation pages
+// they manage.
+type target struct {
+	platform string // E.g. linkedin or linkedinorg-acme
+	orgID    string // Empty when posting as the member
+}
+
+func (t target) authorURN(personID string) string {
+	if t.orgID != "" {
+		return fmt.Sprintf("urn:li:organization:%s", t.orgID)
+	}
+	return fmt.Sprintf("urn:li:person:%s", personID)
+}
+
 func Post(ctx context.Context, args config.Args, sizeLimit int, en entry.Entry) error {
-	err := post(ctx, args, sizeLimit, en)
+	return postWithRetry(ctx, args, sizeLimit, en, target{platform: "linkedin"})
+}
+
+// PostAsOrganization posts the entry as the organisation page configured as org
+// in LinkedInOrgs. This requires the w_organization_social scope.
+func PostAsOrganization(ctx context.Context, args config.Args, sizeLimit int, en entry.Entry, org string) error {
+	orgID, ok := args.Config.LinkedInOrg(org)
+	if !ok {
+		return fmt.Errorf("no LinkedIn organisation '%s' configured in LinkedInOrgs", org)
+	}
+	return postWithRetry(ctx, args, sizeLimit, en, target{platform: config.LinkedInOrgPrefix + org, orgID: orgID})
+}
+
+func postWithRetry(ctx context.Context, args config.Args, sizeLimit int, en entry.Entry, t target) error {
+	err := post(ctx, args, sizeLimit, en, t)
 	if errors.Is(err, errUnauthorized) {
 		colour.Infoln(err, "=> trying to refresh LinkedIn access token")
 		args.Config.LinkedInAccessToken = "" // Reset the token
-		return post(ctx, args, sizeLimit, en)
+		return post(ctx, args, sizeLimit, en, t)
 	}
 	return err
 }
 
-func post(ctx context.Context, args config.Args, sizeLimit int, en entry.Entry) error {
+func post(ctx context.Context, args config.Args, sizeLimit int, en entry.Entry, t target) error {
+	// The card may have been changed on a previous attempt.
+	if current, err := entry.New(en.Path); err == nil {
+		en.FrontMatter.Card = current.FrontMatter.Card
+	}
 	if args.DryRun {
 		colour.Infoln("Not posting", en, "to LinkedIn as dry-run enabled")
 	}
 
 	timeout := linkedInTimeout
-	if args.Config.LinkedInAccessToken == "" {
-		// Refreshing access token requires more time due to human interaction
+	if !oauth2.CanRefresh(args.Config) {
+		// Without a refresh token, authorising again may be needed, which requires
+		// more time due to human interaction
 		timeout = 1 * time.Minute
 	}
 	newCtx, cancel := context.WithTimeout(ctx, timeout)
@@ -59,7 +99,7 @@ func post(ctx context.Context, args config.Args, sizeLimit int, en entry.Entry)
 	if err != nil {
 		return err
 	}
-	content, urls, err := en.ContentWithLimit(sizeLimit)
+	content, urls, err := en.ContentWithLimit(t.platform, sizeLimit, args.Filters)
 	if err != nil {
 		return err
 	}
@@ -67,29 +107,71 @@ func post(ctx context.Context, args config.Args, sizeLimit int, en entry.Entry)
 	newCtx, cancel = context.WithTimeout(ctx, linkedInTimeout)
 	defer cancel()
 
-	prev, err := NewPreview(newCtx, args, urls)
+	prev, err := preview.New(newCtx, args, urls)
+	if err != nil {
+		return err
+	}
+	// The card chosen on a previous attempt, if any.
+	if withCard, err := prev.WithCard(ctx, args, en.FrontMatter.Card); err != nil {
+		colour.Infoln("Ignoring the card of", en.Path, "due to", err)
+	} else {
+		prev = withCard
+	}
+
+	images, err := en.Images()
 	if err != nil {
 		return err
 	}
 
+	var options []prompt.Option
+	// LinkedIn posts either have got a document, images or a card.
+	if len(urls) > 0 && len(images) == 0 && en.FrontMatter.Document.Path == "" {
+		options = append(options, prev.Option(ctx, args, &en))
+	}
 	question := "Do you want to post this message to Linkedin?"
-	if content, err = prompt.FileAction(question, content, en.Path, prompt.RandomOption); err != nil {
+	if t.orgID != "" {
+		question = fmt.Sprintf("Do you want to post this message to the Linkedin page %s?", t.platform)
+	}
+	if _, err = prompt.FileActionWith(question, content, en.Path, prompt.RandomOption, options...); err != nil {
+		return err
+	}
+	// The entry may have been edited, re-read and re-render it.
+	if content, _, err = en.ContentWithLimit(t.platform, sizeLimit, args.Filters); err != nil {
+		return err
+	}
+	if current, err := entry.New(en.Path); err == nil {
+		en.FrontMatter = current.FrontMatter
+	}
+	if images, err = entryImages(ctx, args, en); err != nil {
 		return err
 	}
 
-	newCtx, cancel = context.WithTimeout(ctx, linkedInTimeout)
+	// Uploading and processing the document, the images or the thumbnail takes its time.
+	timeout = linkedInTimeout + time.Duration(len(images)+1)*imageTimeout
+	if en.FrontMatter.Document.Path != "" {
+		timeout = linkedInTimeout + documentTimeout
+	}
+	newCtx, cancel = context.WithTimeout(ctx, timeout)
 	defer cancel()
-	return postMessageToLinkedInAPI(newCtx, personID, accessToken, content, prev, args.Config.LinkedInVersion)
+	media := media{images: images, document: en.FrontMatter.Document, gosDir: args.GosDir}
+	return postMessageToLinkedInAPI(newCtx, t.authorURN(personID), accessToken, content, media, prev, args.Config.LinkedInVersion)
+}
+
+// media is what is attached to a post: a document, or images. Without any, the
+// post gets the card of the first link.
+type media struct {
+	images   []markdown.Image
+	document entry.Document
+	gosDir   string // Local document paths are relative to it
 }
 
 // https://learn.microsoft.com/en-us/linkedin/marketing/community-management/shares/posts-api
-func postMessageToLinkedInAPI(ctx context.Context, personID, accessToken, content string, prev preview, liVersion string) error {
+func postMessageToLinkedInAPI(ctx context.Context, authorURN, accessToken, content string, m media, prev preview.Preview, liVersion string) error {
 	const linkedInPostsURL = "https://api.linkedin.com/rest/posts"
 
-	personURN := fmt.Sprintf("urn:li:person:%s", personID)
 	post := map[string]interface{}{
-		"author":     personURN,
-		"commentary": escapeLinkedInText(content),
+		"author":     authorURN,
+		"commentary": textlen.EscapeLinkedIn(content),
 		"visibility": "PUBLIC",
 		"distribution": map[string]interface{}{
 			"feedDistribution":               "MAIN_FEED",
@@ -100,18 +182,23 @@ func postMessageToLinkedInAPI(ctx context.Context, personID, accessToken, conten
 		"isReshareDisabledByAuthor": false,
 	}
 
-	article := map[string]interface{}{}
-	if thumbnailPath, ok := prev.Thumbnail(); ok {
-		thumbnailURN, err := postImageToLinkedInAPI(ctx, personURN, accessToken, thumbnailPath, liVersion)
-		if err != nil {
-			return err
-		}
-		article["thumbnail"] = thumbnailURN
+	var (
+		postContent map[string]interface{}
+		err         error
+	)
+	switch {
+	case m.document.Path != "":
+		postContent, err = postDocumentToLinkedInAPI(ctx, authorURN, accessToken, m.gosDir, m.document, liVersion)
+	case len(m.images) > 0:
+		postContent, err = postImagesToLinkedInAPI(ctx, authorURN, accessToken, m.images, liVersion)
+	default:
+		postContent, err = postArticleToLinkedInAPI(ctx, authorURN, accessToken, prev, liVersion)
+	}
+	if err != nil {
+		return err
 	}
-	if title, url, ok := prev.TitleAndURL(); ok {
-		article["title"] = title
-		article["source"] = url
-		post["content"] = map[string]interface{}{"article": article}
+	if postContent != nil {
+		post["content"] = postContent
 	}
 
 	payload, err := json.Marshal(post)
@@ -149,6 +236,8 @@ func postMessageToLinkedInAPI(ctx context.Context, personID, accessToken, conten
 		err = fmt.Errorf("failed to post to LinkedIn. Status: %s: %s", resp.Status, string(body))
 		if resp.StatusCode == http.StatusUnauthorized {
 			err = errors.Join(err, errUnauthorized)
+		} else if resp.StatusCode == http.StatusForbidden && strings.HasPrefix(authorURN, "urn:li:organization:") {
+			err = fmt.Errorf("%w; posting as an organisation requires an admin role on its page and the w_organization_social scope (remove LinkedInAccessToken from the config to authorise again)", err)
 		} else if resp.StatusCode == http.StatusUpgradeRequired {
 			// 426 often indicates a non-active LinkedIn-Version header.
 			// Provide a clear hint to configure a valid version.
@@ -158,29 +247,59 @@ func postMessageToLinkedInAPI(ctx context.Context, personID, accessToken, conten
 	return err
 }
 
+// Returns the article content (the card) of the first link, if any. The thumbnail
+// is uploaded as an image.
+func postArticleToLinkedInAPI(ctx context.Context, authorURN, accessToken string, prev preview.Preview, liVersion string) (map[string]interface{}, error) {
+	title, url, ok := prev.TitleAndURL()
+	if !ok {
+		return nil, nil
+	}
+	article := map[string]interface{}{"title": title, "source": url}
+	if thumbnailPath, ok := prev.Thumbnail(); ok {
+		thumbnailURN, err := postImageToLinkedInAPI(ctx, authorURN, accessToken, thumbnailPath, liVersion)
+		if err != nil {
+			return nil, err
+		}
+		article["thumbnail"] = thumbnailURN
+	}
+	if description, ok := prev.Description(); ok {
+		article["description"] = description
+	}
+	return map[string]interface{}{"article": article}, nil
+}
+
 // https://learn.microsoft.com/en-us/linkedin/marketing/community-management/shares/images-api
-func postImageToLinkedInAPI(ctx context.Context, personURN, accessToken, imagePath string, liVersion string) (string, error) {
-	uploadURL, imageURN, err := initializeImageUpload(ctx, personURN, accessToken, liVersion)
+func postImageToLinkedInAPI(ctx context.Context, ownerURN, accessToken, imagePath string, liVersion string) (string, error) {
+	return uploadMedia(ctx, imagesAPI, ownerURN, accessToken, imagePath, liVersion)
+}
+
+// Uploads the image or document and waits until LinkedIn has processed it.
+// Returns its URN.
+func uploadMedia(ctx context.Context, api mediaAPI, ownerURN, accessToken, filePath string, liVersion string) (string, error) {
+	uploadURL, urn, err := initializeUpload(ctx, api, ownerURN, accessToken, liVersion)
 	if err != nil {
-		return imageURN, err
+		return urn, err
+	}
+	if err := performUpload(ctx, filePath, uploadURL, accessToken); err != nil {
+		return urn, err
 	}
-	return imageURN, performImageUpload(ctx, imagePath, uploadURL, accessToken)
+	return urn, waitForMedia(ctx, api, urn, accessToken, liVersion)
 }
 
-func initializeImageUpload(ctx context.Context, personURN, accessToken string, liVersion string) (string, string, error) {
-	const linkedInAPIURL = "https://api.linkedin.com/rest/images?action=initializeUpload"
+func initializeUpload(ctx context.Context, api mediaAPI, ownerURN, accessToken string, liVersion string) (string, string, error) {
+	linkedInAPIURL := linkedInRestURL + string(api) + "?action=initializeUpload"
 
 	type InitializeUploadRequest struct {
 		Owner string `json:"owner"`
 	}
 	requestBody, err := json.Marshal(map[string]interface{}{
-		"initializeUploadRequest": InitializeUploadRequest{Owner: personURN},
+		"initializeUploadRequest": InitializeUploadRequest{Owner: ownerURN},
 	})
 	if err != nil {
 		return "", "", fmt.Errorf("error creating request body: %w", err)
 	}
 
-	// Initialize image upload
+	// Initialize the upload
 	req, err := http.NewRequestWithContext(ctx, "POST", linkedInAPIURL, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return "", "", fmt.Errorf("error creating request: %w", err)
@@ -207,7 +326,7 @@ func initializeImageUpload(ctx context.Context, personURN, accessToken string, l
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		err := fmt.Errorf("image upload initialization failed. Status: %s: %s", resp.Status, string(body))
+		err := fmt.Errorf("%s upload initialization failed. Status: %s: %s", api, resp.Status, string(body))
 		if resp.StatusCode == http.StatusUnauthorized {
 			err = errors.Join(err, errUnauthorized)
 		} else if resp.StatusCode == http.StatusUpgradeRequired {
@@ -222,6 +341,7 @@ func initializeImageUpload(ctx context.Context, personURN, accessToken string, l
 		Value struct {
 			UploadURL string `json:"uploadUrl"`
 			Image     string `json:"image"`
+			Document  string `json:"document"`
 		} `json:"value"`
 	}
 	var response InitializeUploadResponse
@@ -229,10 +349,13 @@ func initializeImageUpload(ctx context.Context, personURN, accessToken string, l
 		return "", "", fmt.Errorf("error decoding response: %w", err)
 	}
 
+	if api == documentsAPI {
+		return response.Value.UploadURL, response.Value.Document, nil
+	}
 	return response.Value.UploadURL, response.Value.Image, nil
 }
 
-func performImageUpload(ctx context.Context, imagePath, uploadURL, accessToken string) error {
+func performUpload(ctx context.Context, imagePath, uploadURL, accessToken string) error {
 	file, err := os.Open(imagePath)
 	if err != nil {
 		return err
@@ -240,19 +363,21 @@ func performImageUpload(ctx context.Context, imagePath, uploadURL, accessToken s
 	defer func() {
 		if err := file.Close(); err != nil {
 			// Log the error but don't fail the operation since we've already read the data
-			colour.Errorln("Error closing image file:", err)
+			colour.Errorln("Error closing file:", err)
 		}
 	}()
 
-	imageData, err := io.ReadAll(file)
+	info, err := file.Stat()
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "PUT", uploadURL, bytes.NewBuffer(imageData))
+	// Stream the file instead of reading it into memory.
+	req, err := http.NewRequestWithContext(ctx, "PUT", uploadURL, file)
 	if err != nil {
 		return fmt.Errorf("error creating upload request: %w", err)
 	}
+	req.ContentLength = info.Size()
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("Content-Type", "application/octet-stream")
 
@@ -0,0 +1,95 @@
+package linkedin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/colour"
+)
+
+const linkedInRestURL = "https://api.linkedin.com/rest/"
+
+// How often to check whether LinkedIn has processed an uploaded image or document.
+const mediaPollInterval = time.Second
+
+// mediaAPI is the LinkedIn API of an uploaded media type.
+type mediaAPI string
+
+const (
+	imagesAPI    mediaAPI = "images"
+	documentsAPI mediaAPI = "documents"
+)
+
+var errProcessingFailed = errors.New("LinkedIn failed to process the upload")
+
+// Polls the status of the uploaded image or document until LinkedIn has processed
+// it, as posts referencing media still being processed are rejected.
+func waitForMedia(ctx context.Context, api mediaAPI, urn, accessToken, liVersion string) error {
+	ticker := time.NewTicker(mediaPollInterval)
+	defer ticker.Stop()
+	for {
+		status, err := mediaStatus(ctx, api, urn, accessToken, liVersion)
+		if err != nil {
+			return err
+		}
+		switch status {
+		case "AVAILABLE":
+			return nil
+		case "PROCESSING_FAILED":
+			return fmt.Errorf("%s: %w", urn, errProcessingFailed)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s still %s: %w", urn, status, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// https://learn.microsoft.com/en-us/linkedin/marketing/community-management/shares/images-api#get-a-single-image
+// https://learn.microsoft.com/en-us/linkedin/marketing/community-management/shares/documents-api#get-a-single-document
+func mediaStatus(ctx context.Context, api mediaAPI, urn, accessToken, liVersion string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", linkedInRestURL+string(api)+"/"+url.PathEscape(urn), nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %w", err)
+	}
+	addCommonHeaders(req, accessToken, liVersion)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error sending request: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			// Log the error but don't fail the operation since we've already read the data
+			colour.Errorln("Error closing response body:", err)
+		}
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("%s status request failed. Status: %s: %s", api, resp.Status, string(body))
+		if resp.StatusCode == http.StatusUnauthorized {
+			err = errors.Join(err, errUnauthorized)
+		}
+		return "", err
+	}
+
+	var response struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("error decoding response: %w", err)
+	}
+	return response.Status, nil
+}
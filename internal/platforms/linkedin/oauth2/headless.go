@@ -0,0 +1,190 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/prompt"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// The pending headless authorisation, in the config directory.
+	pendingFile = "linkedin-oauth2-pending.json"
+	// LinkedIn authorisation codes expire after 30 minutes.
+	pendingTTL      = 30 * time.Minute
+	exchangeTimeout = 10 * time.Second
+)
+
+var (
+	errNoPending     = errors.New("no pending LinkedIn authorisation, run 'gos -headless auth login linkedin' first")
+	errLoginRequired = errors.New("LinkedIn authorisation required, run 'gos auth login linkedin' (with -headless without a local browser)")
+)
+
+// pending is the state and PKCE verifier of a headless authorisation. It is
+// persisted, so that the code can also be passed later, e.g. from another SSH
+// session with "gos auth login linkedin -code ...".
+type pending struct {
+	State    string
+	Verifier string
+	Created  time.Time
+}
+
+// AuthorizeWithCode completes the pending headless authorisation with the code,
+// or with the whole URL redirected to, and persists the token in the config.
+func AuthorizeWithCode(ctx context.Context, args config.Args, code string) error {
+	p, err := loadPending(args)
+	if err != nil {
+		return err
+	}
+	a := &authorization{config: newOAuthConfig(args.Config), state: p.State, verifier: p.Verifier}
+	token, err := a.complete(ctx, code)
+	if err != nil {
+		return err
+	}
+	if _, err := saveToken(ctx, args, args.Config, token); err != nil {
+		return err
+	}
+	removePending(args)
+	colour.Successfln("Successfully authorised gos for LinkedIn")
+	return nil
+}
+
+// Authorize authorises gos for LinkedIn again, in the browser or headless,
+// regardless of the current tokens.
+func Authorize(ctx context.Context, args config.Args) error {
+	token, err := authorize(ctx, args, newOAuthConfig(args.Config), true)
+	if err != nil {
+		return err
+	}
+	if _, err := saveToken(ctx, args, args.Config, token); err != nil {
+		return err
+	}
+	colour.Successfln("Successfully authorised gos for LinkedIn")
+	return nil
+}
+
+// Lets the member authorise gos on any machine: the authorisation URL is printed,
+// and the URL the browser is redirected to (or just its code) is pasted back.
+func authorizeHeadless(ctx context.Context, args config.Args, oauthConfig *oauth2.Config) (*oauth2.Token, error) {
+	a, err := newAuthorization(oauthConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.savePending(args); err != nil {
+		return nil, err
+	}
+	colour.Infoln("Open the following URL in a browser on any machine to authorise gos:")
+	fmt.Println(a.authCodeURL())
+	colour.Infoln("The browser is then redirected to", oauthConfig.RedirectURL,
+		"which may fail to load. That's fine, copy the URL from the address bar.")
+	colour.Infoln("Alternatively, pass it later on with: gos auth login linkedin -code URL")
+
+	input, err := prompt.Input("URL redirected to (or its code):")
+	if err != nil {
+		return nil, err
+	}
+	token, err := a.complete(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	removePending(args)
+	return token, nil
+}
+
+// Completes the authorisation with the pasted callback URL or code.
+func (a *authorization) complete(ctx context.Context, input string) (*oauth2.Token, error) {
+	code, state, err := parseCallback(input)
+	if err != nil {
+		return nil, err
+	}
+	// A bare code has got no state, but then it was pasted by the member themselves.
+	if state != "" && !a.validState(state) {
+		return nil, errors.New("invalid OAuth2 state, the URL doesn't belong to the pending authorisation")
+	}
+	// The context may have expired while waiting for the member.
+	exchangeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), exchangeTimeout)
+	defer cancel()
+	return a.exchange(exchangeCtx, code)
+}
+
+// Returns the code and the state of the callback URL, or the bare code without a
+// state.
+func parseCallback(input string) (string, string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", "", errors.New("no OAuth2 code given")
+	}
+	if !strings.Contains(input, "?") {
+		return input, "", nil
+	}
+	u, err := url.Parse(input)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid callback URL: %w", err)
+	}
+	query := u.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		return "", "", fmt.Errorf("LinkedIn OAuth2 failed: %s: %s", errCode, query.Get("error_description"))
+	}
+	code := query.Get("code")
+	if code == "" {
+		return "", "", fmt.Errorf("no code in the callback URL %s", input)
+	}
+	// Only a bare code may come without the state, a callback URL must prove that
+	// it belongs to the pending authorisation.
+	state := query.Get("state")
+	if state == "" {
+		return "", "", fmt.Errorf("no state in the callback URL %s", input)
+	}
+	return code, state, nil
+}
+
+func pendingPath(args config.Args) string {
+	return filepath.Join(filepath.Dir(args.ConfigPath), pendingFile)
+}
+
+// The verifier is a secret, so only the user may read the file.
+func (a *authorization) savePending(args config.Args) error {
+	data, err := json.Marshal(pending{State: a.state, Verifier: a.verifier, Created: time.Now()})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(pendingPath(args)), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(pendingPath(args), data, 0600)
+}
+
+func loadPending(args config.Args) (pending, error) {
+	var p pending
+	data, err := os.ReadFile(pendingPath(args))
+	if errors.Is(err, os.ErrNotExist) {
+		return p, errNoPending
+	}
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("invalid pending LinkedIn authorisation %s: %w", pendingPath(args), err)
+	}
+	if time.Since(p.Created) > pendingTTL {
+		removePending(args)
+		return p, fmt.Errorf("the pending LinkedIn authorisation has expired: %w", errNoPending)
+	}
+	return p, nil
+}
+
+func removePending(args config.Args) {
+	if err := os.Remove(pendingPath(args)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		colour.Errorln("Unable to remove the pending LinkedIn authorisation:", err)
+	}
+}
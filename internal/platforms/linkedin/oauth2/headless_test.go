@@ -0,0 +1,90 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"codeberg.org/snonux/gos/internal/config"
+	"golang.org/x/oauth2"
+)
+
+func TestParseCallback(t *testing.T) {
+	table := map[string]struct {
+		code, state string
+		ok          bool
+	}{
+		"abc123":     {"abc123", "", true},
+		"  abc123\n": {"abc123", "", true},
+		"http://localhost:8080/callback?code=abc&state=xyz":                                     {"abc", "xyz", true},
+		"http://localhost:8080/callback?error=user_cancelled_login&error_description=cancelled": {"", "", false},
+		"http://localhost:8080/callback?state=xyz":                                              {"", "", false},
+		"http://localhost:8080/callback?code=abc":                                               {"", "", false},
+		"": {"", "", false},
+	}
+	for input, expected := range table {
+		code, state, err := parseCallback(input)
+		if (err == nil) != expected.ok {
+			t.Errorf("expected ok=%v for '%s' but got %v", expected.ok, input, err)
+		}
+		if code != expected.code || state != expected.state {
+			t.Errorf("expected code '%s' and state '%s' for '%s' but got '%s' and '%s'",
+				expected.code, expected.state, input, code, state)
+		}
+	}
+}
+
+func TestPending(t *testing.T) {
+	args := config.Args{ConfigPath: filepath.Join(t.TempDir(), "gos.json")}
+	if _, err := loadPending(args); !errors.Is(err, errNoPending) {
+		t.Errorf("expected no pending authorisation but got %v", err)
+	}
+
+	a, err := newAuthorization(&oauth2.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.savePending(args); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(pendingPath(args))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected the pending authorisation to be private but got %v", info.Mode())
+	}
+	p, err := loadPending(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.State != a.state || p.Verifier != a.verifier {
+		t.Errorf("expected the state and verifier to be persisted but got %+v", p)
+	}
+
+	// A callback URL of another authorisation is rejected.
+	if _, err := a.complete(context.Background(), "http://localhost:8080/callback?code=abc&state=other"); err == nil {
+		t.Error("expected an error for a foreign state")
+	}
+	// So is a callback URL without any state.
+	if _, err := a.complete(context.Background(), "http://localhost:8080/callback?code=abc"); err == nil {
+		t.Error("expected an error for a missing state")
+	}
+	removePending(args)
+	if _, err := loadPending(args); !errors.Is(err, errNoPending) {
+		t.Errorf("expected the pending authorisation to be removed but got %v", err)
+	}
+}
+
+func TestAuthorizeHeadlessNotInteractive(t *testing.T) {
+	args := config.Args{ConfigPath: filepath.Join(t.TempDir(), "gos.json"), OAuth2Headless: true}
+	_, err := authorize(context.Background(), args, newOAuthConfig(args.Config), false)
+	if !errors.Is(err, errLoginRequired) {
+		t.Errorf("expected %v but got %v", errLoginRequired, err)
+	}
+	if _, err := os.Stat(pendingPath(args)); !errors.Is(err, os.ErrNotExist) {
+		t.Error("expected no pending authorisation")
+	}
+}
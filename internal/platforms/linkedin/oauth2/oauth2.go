@@ -3,12 +3,12 @@ package oauth2
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os/exec"
-	"runtime"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"codeberg.org/snonux/gos/internal/colour"
@@ -17,18 +17,16 @@ import (
 	"golang.org/x/oauth2/linkedin"
 )
 
-var (
-	oauthConfig      *oauth2.Config
-	oauthAccessToken string
-	oauthPersonID    string
-	errCh            chan error
-	globalCtx        context.Context
+const (
+	defaultRedirectURL = "http://localhost:8080/callback"
+	// The access token is refreshed when it expires within this margin.
+	refreshMargin = 24 * time.Hour
 )
 
-func getOauthPersonID(token *oauth2.Token) (string, error) {
+func getOauthPersonID(ctx context.Context, token *oauth2.Token) (string, error) {
 	const url = "https://api.linkedin.com/v2/userinfo"
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return "", fmt.Errorf("Error creating request:%w", err)
 	}
@@ -64,124 +62,191 @@ func getOauthPersonID(token *oauth2.Token) (string, error) {
 	return user.Sub, nil
 }
 
-func upHandler(w http.ResponseWriter, r *http.Request) {
-	_, _ = w.Write([]byte("I am up!\n"))
-}
+// LinkedInCreds returns the LinkedIn person ID and access token. An access token
+// about to expire (or rejected, when reset by the caller) is refreshed with the
+// refresh token. Only without a valid refresh token, the member is asked to
+// authorise gos in the browser again. The headless authorisation isn't started
+// from here, it requires "gos auth login linkedin". New tokens are persisted in
+// the config.
+func LinkedInCreds(ctx context.Context, args config.Args) (string, string, error) {
+	conf := args.Config
+	if !NeedsRefresh(conf) && conf.LinkedInPersonID != "" {
+		if conf.LinkedInTokenExpiry != 0 {
+			return conf.LinkedInPersonID, conf.LinkedInAccessToken, nil
+		}
+		// The expiry of tokens of older gos versions is unknown, validate them.
+		token := &oauth2.Token{AccessToken: conf.LinkedInAccessToken}
+		if _, err := getOauthPersonID(ctx, token); err == nil {
+			return conf.LinkedInPersonID, conf.LinkedInAccessToken, nil
+		}
+	}
 
-func oauthIndexHandler(w http.ResponseWriter, r *http.Request) {
-	url := oauthConfig.AuthCodeURL("state", oauth2.AccessTypeOffline)
-	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
-}
+	oauthConfig := newOAuthConfig(conf)
+	var token *oauth2.Token
+	if CanRefresh(conf) {
+		colour.Infoln("Refreshing the LinkedIn access token")
+		var err error
+		refreshToken := &oauth2.Token{RefreshToken: conf.LinkedInRefreshToken}
+		if token, err = oauthConfig.TokenSource(ctx, refreshToken).Token(); err != nil {
+			colour.Infoln("Unable to refresh the LinkedIn access token:", err)
+			token = nil
+		}
+	}
+	if token == nil {
+		var err error
+		if token, err = authorize(ctx, args, oauthConfig, false); err != nil {
+			return "", "", err
+		}
+	}
 
-func oauthCallbackHandler(w http.ResponseWriter, r *http.Request) {
-	defer close(errCh)
-	code := r.URL.Query().Get("code")
+	personID, err := saveToken(ctx, args, conf, token)
+	return personID, token.AccessToken, err
+}
 
-	colour.Infoln("Exchanging OAuth2 token")
-	token, err := oauthConfig.Exchange(globalCtx, code)
+// Looks up the person ID of the new token and persists both in the config.
+// The context may have expired while waiting for the member to authorise gos.
+func saveToken(ctx context.Context, args config.Args, conf config.Config, token *oauth2.Token) (string, error) {
+	personCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), exchangeTimeout)
+	defer cancel()
+	personID, err := getOauthPersonID(personCtx, token)
 	if err != nil {
-		_, _ = w.Write([]byte(err.Error()))
-		errCh <- err
-		return
+		return "", err
 	}
-	oauthAccessToken = token.AccessToken
-	_, _ = w.Write([]byte("Successfully fetched LinkedIn access token\n"))
+	storeToken(&conf, token, personID)
+	return personID, conf.WriteToDisk(args.ConfigPath)
+}
 
-	if oauthPersonID, err = getOauthPersonID(token); err != nil {
-		_, _ = w.Write([]byte(err.Error()))
-		errCh <- err
-		return
+// NeedsRefresh returns true if there is no access token, or if it expires soon.
+func NeedsRefresh(conf config.Config) bool {
+	if conf.LinkedInAccessToken == "" {
+		return true
 	}
-	_, _ = w.Write([]byte("Successfully fetched LinkedIn person ID\n"))
+	return conf.LinkedInTokenExpiry != 0 && time.Until(time.Unix(conf.LinkedInTokenExpiry, 0)) < refreshMargin
 }
 
-func LinkedInCreds(ctx context.Context, args config.Args) (string, string, error) {
-	conf := args.Config
-	if conf.LinkedInAccessToken != "" && conf.LinkedInPersonID != "" {
-		// Validate cached token before using it
-		token := &oauth2.Token{AccessToken: conf.LinkedInAccessToken}
-		if _, err := getOauthPersonID(token); err == nil {
-			return conf.LinkedInPersonID, conf.LinkedInAccessToken, nil
-		}
-		// Cached token is invalid, clear it to trigger re-auth
-		conf.LinkedInAccessToken = ""
+// CanRefresh returns true if there is a refresh token which hasn't expired yet.
+func CanRefresh(conf config.Config) bool {
+	if conf.LinkedInRefreshToken == "" {
+		return false
 	}
+	return conf.LinkedInRefreshTokenExpiry == 0 || time.Now().Before(time.Unix(conf.LinkedInRefreshTokenExpiry, 0))
+}
 
-	oauthConfig = &oauth2.Config{
+func newOAuthConfig(conf config.Config) *oauth2.Config {
+	redirectURL := conf.LinkedInRedirectURL
+	if redirectURL == "" {
+		redirectURL = defaultRedirectURL
+	}
+	return &oauth2.Config{
 		ClientID:     conf.LinkedInClientID,
 		ClientSecret: conf.LinkedInSecret,
-		RedirectURL:  conf.LinkedInRedirectURL,
-		Scopes:       []string{"openid", "profile", "w_member_social"},
+		RedirectURL:  redirectURL,
+		Scopes:       Scopes(conf),
 		Endpoint:     linkedin.Endpoint,
 	}
-	errCh = make(chan error)
-	globalCtx = ctx
+}
+
+// Stores the token in the config. A refreshed token may come without a new
+// refresh token, then the current one stays valid.
+func storeToken(conf *config.Config, token *oauth2.Token, personID string) {
+	conf.LinkedInAccessToken = token.AccessToken
+	conf.LinkedInPersonID = personID
+	conf.LinkedInTokenExpiry = 0
+	if !token.Expiry.IsZero() {
+		conf.LinkedInTokenExpiry = token.Expiry.Unix()
+	}
+	if token.RefreshToken != "" {
+		conf.LinkedInRefreshToken = token.RefreshToken
+		conf.LinkedInRefreshTokenExpiry = 0
+	}
+	// LinkedIn returns the lifetime of the refresh token in seconds.
+	var expiresIn int64
+	switch v := token.Extra("refresh_token_expires_in").(type) {
+	case float64:
+		expiresIn = int64(v)
+	case string:
+		expiresIn, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if expiresIn > 0 {
+		conf.LinkedInRefreshTokenExpiry = time.Now().Add(time.Duration(expiresIn) * time.Second).Unix()
+	}
+}
 
-	http.HandleFunc("/", oauthIndexHandler)
-	http.HandleFunc("/callback", oauthCallbackHandler)
-	http.HandleFunc("/up", upHandler)
+// PersonID returns the person ID of the member of the access token, and fails if
+// LinkedIn rejects the token.
+func PersonID(ctx context.Context, accessToken string) (string, error) {
+	return getOauthPersonID(ctx, &oauth2.Token{AccessToken: accessToken})
+}
 
-	colour.Infoln("Listening on http://localhost:8080 for LinkedIn OAuth2")
-	go func() {
-		if err := http.ListenAndServe(":8080", nil); err != nil {
-			errCh <- err
-		}
-	}()
+// TokenInfo is what LinkedIn knows about an access token.
+type TokenInfo struct {
+	Active    bool
+	Scopes    []string
+	ExpiresAt time.Time
+}
 
-	if err := WaitUntilURLIsReachable("http://localhost:8080/up"); err != nil {
-		return "", "", err
-	}
+// Introspect asks LinkedIn about the access token, e.g. its scopes.
+// https://learn.microsoft.com/en-us/linkedin/shared/authentication/token-introspection
+func Introspect(ctx context.Context, conf config.Config) (TokenInfo, error) {
+	const introspectURL = "https://www.linkedin.com/oauth/v2/introspectToken"
 
-	if err := openURLInFirefox(args.OAuth2Browser, "http://localhost:8080"); err != nil {
-		return "", "", err
+	var info TokenInfo
+	form := url.Values{
+		"client_id":     {conf.LinkedInClientID},
+		"client_secret": {conf.LinkedInSecret},
+		"token":         {conf.LinkedInAccessToken},
 	}
+	req, err := http.NewRequestWithContext(ctx, "POST", introspectURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return info, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	var errs error
-	for err := range errCh {
-		errs = errors.Join(errs, err)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return info, fmt.Errorf("error sending request: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			// Log the error but don't fail the operation since we've already read the data
+			colour.Errorln("Error closing response body:", err)
+		}
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return info, fmt.Errorf("error reading response: %w", err)
 	}
-	if errs != nil {
-		return "", "", errs
+	if resp.StatusCode != http.StatusOK {
+		return info, fmt.Errorf("token introspection failed. Status: %s: %s", resp.Status, string(body))
 	}
 
-	conf.LinkedInAccessToken = oauthAccessToken
-	conf.LinkedInPersonID = oauthPersonID
-	return oauthPersonID, oauthAccessToken, conf.WriteToDisk(args.ConfigPath)
-}
-
-func openURLInFirefox(browser, url string) error {
-	colour.Infoln("Opening", url, "in", browser)
-	switch runtime.GOOS {
-	case "windows":
-		cmd := exec.Command("cmd", "/C", "start", browser, url)
-		return cmd.Start()
-	case "darwin":
-		cmd := exec.Command("open", "-a", browser, url)
-		return cmd.Start()
-	default:
-		// Linux and other Unix like (e.g. *BSDs)
-		cmd := exec.Command(browser, url)
-		return cmd.Start()
+	var response struct {
+		Active    bool   `json:"active"`
+		Scope     string `json:"scope"`
+		ExpiresAt int64  `json:"expires_at"`
 	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return info, fmt.Errorf("error decoding response: %w", err)
+	}
+	info.Active = response.Active
+	for _, scope := range strings.Split(response.Scope, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			info.Scopes = append(info.Scopes, scope)
+		}
+	}
+	if response.ExpiresAt > 0 {
+		info.ExpiresAt = time.Unix(response.ExpiresAt, 0)
+	}
+	return info, nil
 }
 
-func WaitUntilURLIsReachable(url string) error {
-	var counter int
-	for counter < 10 {
-		counter++
-		time.Sleep(1 * time.Second)
-		resp, err := http.Get(url)
-
-		if err != nil {
-			colour.Infofln("URL is not reachable: %v", err)
-		} else {
-			colour.Infofln("URL is reachable: %s - Status Code: %d", url, resp.StatusCode)
-			if err := resp.Body.Close(); err != nil {
-				// Log the error but don't fail the operation since we've already read the data
-				colour.Errorln("Error closing response body:", err)
-			}
-			return nil
-		}
+// Scopes returns the OAuth2 scopes needed: posting as an organisation page also
+// requires w_organization_social.
+func Scopes(conf config.Config) []string {
+	scopes := []string{"openid", "profile", "w_member_social"}
+	if len(conf.LinkedInOrgs) > 0 {
+		scopes = append(scopes, "w_organization_social")
 	}
-	return fmt.Errorf("%s not reachable after %d tries", url, counter)
+	return scopes
 }
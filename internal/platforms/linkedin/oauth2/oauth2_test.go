@@ -0,0 +1,108 @@
+package oauth2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/config"
+	"golang.org/x/oauth2"
+)
+
+func TestCallbackHandler(t *testing.T) {
+	var verifier string
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		verifier = r.PostForm.Get("code_verifier")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"access","refresh_token":"refresh","expires_in":3600,"refresh_token_expires_in":7200}`))
+	}))
+	defer tokenServer.Close()
+
+	a, err := newAuthorization(&oauth2.Config{
+		ClientID: "id",
+		Endpoint: oauth2.Endpoint{TokenURL: tokenServer.URL, AuthStyle: oauth2.AuthStyleInParams},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callback := func(query url.Values) int {
+		rec := httptest.NewRecorder()
+		a.callbackHandler(rec, httptest.NewRequest(http.MethodGet, "/callback?"+query.Encode(), nil))
+		return rec.Code
+	}
+	if code := callback(url.Values{"code": {"code"}, "state": {"forged"}}); code != http.StatusBadRequest {
+		t.Errorf("expected a forged state to be rejected but got %d", code)
+	}
+	select {
+	case r := <-a.result:
+		t.Fatalf("expected a forged state not to end the authorisation but got %v", r)
+	default:
+	}
+
+	if code := callback(url.Values{"code": {"code"}, "state": {a.state}}); code != http.StatusOK {
+		t.Errorf("expected the callback to succeed but got %d", code)
+	}
+	r := <-a.result
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if verifier != a.verifier {
+		t.Errorf("expected the PKCE verifier '%s' but got '%s'", a.verifier, verifier)
+	}
+
+	var conf config.Config
+	storeToken(&conf, r.token, "person")
+	if conf.LinkedInAccessToken != "access" || conf.LinkedInRefreshToken != "refresh" || conf.LinkedInPersonID != "person" {
+		t.Errorf("unexpected stored token %+v", conf)
+	}
+	if expiry := time.Until(time.Unix(conf.LinkedInRefreshTokenExpiry, 0)); expiry < time.Hour || expiry > 2*time.Hour {
+		t.Errorf("unexpected refresh token expiry in %v", expiry)
+	}
+	if !NeedsRefresh(conf) {
+		t.Error("expected a token expiring within the refresh margin to need a refresh")
+	}
+	if !CanRefresh(conf) {
+		t.Error("expected the token to be refreshable")
+	}
+}
+
+func TestNeedsRefresh(t *testing.T) {
+	table := map[string]struct {
+		conf     config.Config
+		expected bool
+	}{
+		"no token":      {config.Config{}, true},
+		"unknown":       {config.Config{LinkedInAccessToken: "a"}, false},
+		"valid":         {config.Config{LinkedInAccessToken: "a", LinkedInTokenExpiry: time.Now().Add(30 * 24 * time.Hour).Unix()}, false},
+		"expiring soon": {config.Config{LinkedInAccessToken: "a", LinkedInTokenExpiry: time.Now().Add(time.Hour).Unix()}, true},
+		"expired":       {config.Config{LinkedInAccessToken: "a", LinkedInTokenExpiry: time.Now().Add(-time.Hour).Unix()}, true},
+	}
+	for name, tc := range table {
+		if got := NeedsRefresh(tc.conf); got != tc.expected {
+			t.Errorf("%s: expected %v but got %v", name, tc.expected, got)
+		}
+	}
+}
+
+func TestListenAddr(t *testing.T) {
+	table := map[string]string{
+		"http://localhost:8080/callback": "localhost:8080",
+		"http://127.0.0.1:9999/cb":       "127.0.0.1:9999",
+		"http://localhost/callback":      "localhost:80",
+	}
+	for rawURL, expected := range table {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := listenAddr(u); got != expected {
+			t.Errorf("expected %s for %s but got %s", expected, rawURL, got)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/browser"
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/config"
+	"golang.org/x/oauth2"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// authorization is an interactive authorisation of gos by the LinkedIn member,
+// using the authorisation code flow with a random state and PKCE.
+type authorization struct {
+	config   *oauth2.Config
+	state    string
+	verifier string
+	result   chan authResult
+	once     sync.Once
+}
+
+type authResult struct {
+	token *oauth2.Token
+	err   error
+}
+
+func newAuthorization(oauthConfig *oauth2.Config) (*authorization, error) {
+	state := make([]byte, 32)
+	if _, err := rand.Read(state); err != nil {
+		return nil, err
+	}
+	return &authorization{
+		config:   oauthConfig,
+		state:    base64.RawURLEncoding.EncodeToString(state),
+		verifier: oauth2.GenerateVerifier(),
+		result:   make(chan authResult, 1),
+	}, nil
+}
+
+// Lets the member authorise gos in the browser. The callback is served by a
+// dedicated server on the address of the redirect URL, which is shut down again
+// afterwards. Without a local browser, the headless authorisation is used if
+// interactive, i.e. run by "gos auth login linkedin". Otherwise, e.g. when
+// posting, it fails instead of waiting for the member to paste the URL.
+func authorize(ctx context.Context, args config.Args, oauthConfig *oauth2.Config, interactive bool) (*oauth2.Token, error) {
+	if args.OAuth2Headless {
+		if !interactive {
+			return nil, errLoginRequired
+		}
+		return authorizeHeadless(ctx, args, oauthConfig)
+	}
+	redirect, err := url.Parse(oauthConfig.RedirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LinkedInRedirectURL %s: %w", oauthConfig.RedirectURL, err)
+	}
+	if redirect.Path == "" || redirect.Path == "/" {
+		return nil, fmt.Errorf("LinkedInRedirectURL %s needs a callback path, e.g. %s", oauthConfig.RedirectURL, defaultRedirectURL)
+	}
+	a, err := newAuthorization(oauthConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", a.indexHandler)
+	mux.HandleFunc(redirect.Path, a.callbackHandler)
+	mux.HandleFunc("/up", upHandler)
+
+	ln, err := net.Listen("tcp", listenAddr(redirect))
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen for the LinkedIn OAuth2 callback: %w", err)
+	}
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			a.done(nil, err)
+		}
+	}()
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			colour.Errorln("Error shutting down the OAuth2 server:", err)
+		}
+	}()
+
+	startURL := (&url.URL{Scheme: redirect.Scheme, Host: redirect.Host, Path: "/"}).String()
+	colour.Infoln("Listening on", startURL, "for LinkedIn OAuth2")
+	if err := browser.Open(args.OAuth2Browser, startURL); err != nil {
+		colour.Warnln("Unable to open", startURL, "in", args.OAuth2Browser+":", err)
+		if !interactive {
+			return nil, errLoginRequired
+		}
+		return authorizeHeadless(ctx, args, oauthConfig)
+	}
+
+	select {
+	case r := <-a.result:
+		return r.token, r.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("LinkedIn OAuth2 not completed: %w", ctx.Err())
+	}
+}
+
+// Returns the address to listen on, the host and port of the redirect URL.
+func listenAddr(redirect *url.URL) string {
+	port := redirect.Port()
+	if port == "" {
+		port = "80"
+		if redirect.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(redirect.Hostname(), port)
+}
+
+// Finishes the authorisation, only the first result counts.
+func (a *authorization) done(token *oauth2.Token, err error) {
+	a.once.Do(func() {
+		a.result <- authResult{token, err}
+	})
+}
+
+func upHandler(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte("I am up!\n"))
+}
+
+func (a *authorization) indexHandler(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, a.authCodeURL(), http.StatusTemporaryRedirect)
+}
+
+func (a *authorization) authCodeURL() string {
+	return a.config.AuthCodeURL(a.state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(a.verifier))
+}
+
+func (a *authorization) callbackHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	// Requests not started by gos itself are rejected, but don't end the authorisation.
+	if !a.validState(query.Get("state")) {
+		http.Error(w, "Invalid OAuth2 state", http.StatusBadRequest)
+		return
+	}
+	if errCode := query.Get("error"); errCode != "" {
+		err := fmt.Errorf("LinkedIn OAuth2 failed: %s: %s", errCode, query.Get("error_description"))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		a.done(nil, err)
+		return
+	}
+
+	token, err := a.exchange(r.Context(), query.Get("code"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		a.done(nil, err)
+		return
+	}
+	_, _ = w.Write([]byte("Successfully fetched LinkedIn access token, you can close this window now\n"))
+	a.done(token, nil)
+}
+
+func (a *authorization) validState(state string) bool {
+	return subtle.ConstantTimeCompare([]byte(state), []byte(a.state)) == 1
+}
+
+// Exchanges the authorisation code for the token, proving with the PKCE verifier
+// that gos started the authorisation.
+func (a *authorization) exchange(ctx context.Context, code string) (*oauth2.Token, error) {
+	colour.Infoln("Exchanging OAuth2 token")
+	return a.config.Exchange(ctx, code, oauth2.VerifierOption(a.verifier))
+}
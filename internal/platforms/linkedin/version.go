@@ -0,0 +1,53 @@
+package linkedin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"codeberg.org/snonux/gos/internal/colour"
+)
+
+// ErrVersionUnknown is returned by CheckVersion if LinkedIn's response doesn't tell
+// whether the version is active, e.g. as the access token was rejected.
+var ErrVersionUnknown = errors.New("LinkedIn-Version not known to be active")
+
+// CheckVersion checks whether LinkedIn accepts the LinkedIn-Version header. Once a
+// version isn't active anymore, all requests are rejected with 426 Upgrade Required.
+// As the resource requested doesn't exist, the version is only active on a 404 (or
+// any 2xx). It returns the version checked.
+func CheckVersion(ctx context.Context, accessToken, liVersion string) (string, error) {
+	if liVersion == "" {
+		liVersion = defaultVersion
+	}
+	// Any resource will do, only the version is checked before everything else.
+	req, err := http.NewRequestWithContext(ctx, "GET", linkedInRestURL+string(imagesAPI)+"/"+url.PathEscape("urn:li:image:gos"), nil)
+	if err != nil {
+		return liVersion, fmt.Errorf("error creating request: %w", err)
+	}
+	addCommonHeaders(req, accessToken, liVersion)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return liVersion, fmt.Errorf("%w: error sending request: %w", ErrVersionUnknown, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			// Log the error but don't fail the operation since we've already read the data
+			colour.Errorln("Error closing response body:", err)
+		}
+	}()
+	switch {
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return liVersion, nil
+	case resp.StatusCode == http.StatusUpgradeRequired:
+		body, _ := io.ReadAll(resp.Body)
+		return liVersion, fmt.Errorf("LinkedIn-Version %s is not active, set an active 'LinkedInVersion' in the config: %s", liVersion, string(body))
+	default:
+		return liVersion, fmt.Errorf("%w: unexpected status %s", ErrVersionUnknown, resp.Status)
+	}
+}
@@ -0,0 +1,194 @@
+package mastodon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"codeberg.org/snonux/gos/internal/browser"
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/prompt"
+	"golang.org/x/oauth2"
+)
+
+const (
+	appName    = "gos"
+	appWebsite = "https://codeberg.org/snonux/gos"
+	appScopes  = "read:accounts write:statuses"
+	// Mastodon shows the authorisation code to the user, instead of redirecting.
+	oobRedirectURI = "urn:ietf:wg:oauth:2.0:oob"
+	statusesPath   = "/api/v1/statuses"
+)
+
+// Credentials is what the instance knows about the access token.
+type Credentials struct {
+	Account string   // E.g. @paul@fosstodon.org
+	Scopes  []string // Empty, if the instance doesn't tell
+}
+
+// InstanceURL returns the base URL of the instance, e.g. https://fosstodon.org for
+// the MastodonURL https://fosstodon.org/api/v1/statuses, or for fosstodon.org.
+func InstanceURL(rawURL string) (string, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid Mastodon URL %s: %w", rawURL, err)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid Mastodon URL %s: no host", rawURL)
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// VerifyCredentials checks the access token with the instance, and returns the
+// account and the scopes of the token.
+func VerifyCredentials(ctx context.Context, conf config.Config) (Credentials, error) {
+	var creds Credentials
+	if conf.MastodonURL == "" || conf.MastodonAccessToken == "" {
+		return creds, errors.New("no MastodonURL or MastodonAccessToken configured")
+	}
+	instance, err := InstanceURL(conf.MastodonURL)
+	if err != nil {
+		return creds, err
+	}
+
+	var account struct {
+		Acct string `json:"acct"`
+	}
+	if err := getJSON(ctx, instance+"/api/v1/accounts/verify_credentials", conf.MastodonAccessToken, &account); err != nil {
+		return creds, err
+	}
+	_, host, _ := strings.Cut(instance, "://")
+	creds.Account = fmt.Sprintf("@%s@%s", account.Acct, host)
+
+	// Only newer instances tell the scopes of the app.
+	var app struct {
+		Scopes []string `json:"scopes"`
+	}
+	if err := getJSON(ctx, instance+"/api/v1/apps/verify_credentials", conf.MastodonAccessToken, &app); err == nil {
+		creds.Scopes = app.Scopes
+	}
+	return creds, nil
+}
+
+// Login registers gos as an app on the instance and lets the user authorise it.
+// The access token and the statuses URL of the instance are persisted in the config.
+func Login(ctx context.Context, args config.Args, instance string) error {
+	instance, err := InstanceURL(instance)
+	if err != nil {
+		return err
+	}
+	newCtx, cancel := context.WithTimeout(ctx, mastodonTimeout)
+	defer cancel()
+	clientID, clientSecret, err := registerApp(newCtx, instance)
+	if err != nil {
+		return err
+	}
+
+	oauthConfig := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  oobRedirectURI,
+		Scopes:       strings.Fields(appScopes),
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   instance + "/oauth/authorize",
+			TokenURL:  instance + "/oauth/token",
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}
+	verifier := oauth2.GenerateVerifier()
+	authURL := oauthConfig.AuthCodeURL("", oauth2.S256ChallengeOption(verifier))
+	colour.Infoln("Open the following URL in a browser to authorise gos, then paste the code shown:")
+	fmt.Println(authURL)
+	if !args.OAuth2Headless {
+		if err := browser.Open(args.OAuth2Browser, authURL); err != nil {
+			colour.Warnln("Unable to open the browser:", err)
+		}
+	}
+	code, err := prompt.Input("Authorisation code:")
+	if err != nil {
+		return err
+	}
+
+	newCtx, cancel = context.WithTimeout(ctx, mastodonTimeout)
+	defer cancel()
+	token, err := oauthConfig.Exchange(newCtx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return err
+	}
+	conf := args.Config
+	conf.MastodonURL = instance + statusesPath
+	conf.MastodonAccessToken = token.AccessToken
+	creds, err := VerifyCredentials(newCtx, conf)
+	if err != nil {
+		return err
+	}
+	if err := conf.WriteToDisk(args.ConfigPath); err != nil {
+		return err
+	}
+	colour.Successfln("Logged in to %s as %s", instance, creds.Account)
+	return nil
+}
+
+// https://docs.joinmastodon.org/methods/apps/#create
+func registerApp(ctx context.Context, instance string) (string, string, error) {
+	form := url.Values{
+		"client_name":   {appName},
+		"redirect_uris": {oobRedirectURI},
+		"scopes":        {appScopes},
+		"website":       {appWebsite},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", instance+"/api/v1/apps", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var app struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	if err := doJSON(req, &app); err != nil {
+		return "", "", fmt.Errorf("failed to register gos on %s: %w", instance, err)
+	}
+	return app.ClientID, app.ClientSecret, nil
+}
+
+func getJSON(ctx context.Context, url, accessToken string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return doJSON(req, v)
+}
+
+func doJSON(req *http.Request, v any) error {
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			// Log the error but don't fail the operation since we've already read the data
+			colour.Errorln("Error closing response body:", err)
+		}
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, string(body))
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,89 @@
+package mastodon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"codeberg.org/snonux/gos/internal/config"
+)
+
+func TestInstanceURL(t *testing.T) {
+	table := map[string]string{
+		"fosstodon.org":                             "https://fosstodon.org",
+		"https://fosstodon.org":                     "https://fosstodon.org",
+		"https://fosstodon.org/api/v1/statuses":     "https://fosstodon.org",
+		"http://localhost:3000/api/v1/statuses?x=1": "http://localhost:3000",
+	}
+	for rawURL, expected := range table {
+		got, err := InstanceURL(rawURL)
+		if err != nil {
+			t.Error(err)
+		}
+		if got != expected {
+			t.Errorf("expected %s for %s but got %s", expected, rawURL, got)
+		}
+	}
+}
+
+func TestVerifyCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, `{"error":"The access token is invalid"}`, http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/accounts/verify_credentials":
+			_, _ = w.Write([]byte(`{"acct":"paul"}`))
+		case "/api/v1/apps/verify_credentials":
+			_, _ = w.Write([]byte(`{"name":"gos","scopes":["read:accounts","write:statuses"]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	conf := config.Config{MastodonURL: server.URL + statusesPath, MastodonAccessToken: "secret"}
+	creds, err := VerifyCredentials(context.Background(), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := strings.TrimPrefix(server.URL, "http://")
+	if creds.Account != "@paul@"+host {
+		t.Errorf("expected account @paul@%s but got %s", host, creds.Account)
+	}
+	if !slices.Equal(creds.Scopes, []string{"read:accounts", "write:statuses"}) {
+		t.Errorf("unexpected scopes %v", creds.Scopes)
+	}
+
+	conf.MastodonAccessToken = "wrong"
+	if _, err := VerifyCredentials(context.Background(), conf); err == nil {
+		t.Error("expected an error for an invalid token")
+	}
+}
+
+func TestRegisterApp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if r.URL.Path != "/api/v1/apps" || r.PostForm.Get("redirect_uris") != oobRedirectURI ||
+			r.PostForm.Get("scopes") != appScopes {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"client_id":"id","client_secret":"secret"}`))
+	}))
+	defer server.Close()
+
+	clientID, clientSecret, err := registerApp(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientID != "id" || clientSecret != "secret" {
+		t.Errorf("unexpected client credentials %s and %s", clientID, clientSecret)
+	}
+}
@@ -13,12 +13,13 @@ import (
 	"codeberg.org/snonux/gos/internal/config"
 	"codeberg.org/snonux/gos/internal/entry"
 	"codeberg.org/snonux/gos/internal/prompt"
+	"codeberg.org/snonux/gos/internal/series"
 )
 
 const mastodonTimeout = 10 * time.Second
 
 func Post(ctx context.Context, args config.Args, sizeLimit int, en entry.Entry) error {
-	content, _, err := en.ContentWithLimit(sizeLimit)
+	content, _, err := en.ContentWithLimit("mastodon", sizeLimit, args.Filters)
 	if err != nil {
 		return err
 	}
@@ -26,12 +27,26 @@ func Post(ctx context.Context, args config.Args, sizeLimit int, en entry.Entry)
 		colour.Infoln("Not posting", en, "to Mastodon as dry-run enabled")
 		return nil
 	}
-	if content, err = prompt.FileAction("Do you want to post this message to Mastodon?",
+	if _, err = prompt.FileAction("Do you want to post this message to Mastodon?",
 		content, en.Path, prompt.RandomOption); err != nil {
 		return err
 	}
+	// The entry may have been edited, re-read and re-render it.
+	if content, _, err = en.ContentWithLimit("mastodon", sizeLimit, args.Filters); err != nil {
+		return err
+	}
 
 	payload := map[string]string{"status": content}
+	if args.SeriesReply {
+		// Reply to the previous part of the series, so that the parts form a thread.
+		previousID, ok, err := series.PreviousRemoteID(args.GosDir, "mastodon", en)
+		if err != nil {
+			return err
+		}
+		if ok {
+			payload["in_reply_to_id"] = previousID
+		}
+	}
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal payload: %w", err)
@@ -67,5 +82,13 @@ func Post(ctx context.Context, args config.Args, sizeLimit int, en entry.Entry)
 		return fmt.Errorf("unexpected status code: %d\n%s\n",
 			resp.StatusCode, string(body))
 	}
-	return nil
+
+	var status struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		colour.Infoln("Unable to decode the Mastodon status:", err)
+		return nil
+	}
+	return series.RecordPost(args.GosDir, "mastodon", en, status.ID)
 }
@@ -11,7 +11,7 @@ import (
 
 // Psudo platform, not posting really anything.
 func Post(ctx context.Context, args config.Args, sizeLimit int, en entry.Entry) error {
-	content, _, err := en.ContentWithLimit(sizeLimit)
+	content, _, err := en.ContentWithLimit("noop", sizeLimit, args.Filters)
 	if err != nil {
 		return err
 	}
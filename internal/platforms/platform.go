@@ -32,7 +32,12 @@ func New(platformStr string) (Platform, error) {
 	var p Platform
 	name, ok := aliases[strings.ToLower(platformStr)]
 	if !ok {
-		return p, fmt.Errorf("no such platform: '%s'", platformStr)
+		// E.g. linkedinorg-acme, the LinkedIn organisation page configured as acme.
+		org, isOrg := strings.CutPrefix(strings.ToLower(platformStr), config.LinkedInOrgPrefix)
+		if !isOrg || org == "" || strings.ContainsAny(org, ".:") {
+			return p, fmt.Errorf("no such platform: '%s'", platformStr)
+		}
+		name = config.LinkedInOrgPrefix + org
 	}
 	return Platform(name), nil
 }
@@ -51,6 +56,10 @@ func (p Platform) Post(ctx context.Context, args config.Args, sizeLimit int, en
 	case "noop":
 		err = noop.Post(ctx, args, sizeLimit, en)
 	default:
+		if org, ok := strings.CutPrefix(p.String(), config.LinkedInOrgPrefix); ok {
+			err = linkedin.PostAsOrganization(ctx, args, sizeLimit, en, org)
+			break
+		}
 		err = fmt.Errorf("Platform '%s' (not yet) implemented", p)
 	}
 
@@ -75,10 +84,16 @@ func ExpandAliases(shareTag string) (string, error) {
 	dedup := make(map[string]struct{}, len(aliases))
 
 	for _, alias := range parts[1:] {
-		platformStr, ok := aliases[alias]
-		if !ok {
+		// Excluded platforms are prefixed with a dash, e.g. share:-linkedin
+		exclude := strings.HasPrefix(alias, "-")
+		platform, err := New(strings.TrimPrefix(alias, "-"))
+		if err != nil {
 			return "", fmt.Errorf("invalid platform alias '%s' in '%s'", alias, shareTag)
 		}
+		platformStr := platform.String()
+		if exclude {
+			platformStr = "-" + platformStr
+		}
 		if _, ok := dedup[platformStr]; ok {
 			// Duplicate, ignore
 			continue
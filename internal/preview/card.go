@@ -0,0 +1,155 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/entry"
+	"codeberg.org/snonux/gos/internal/prompt"
+	"codeberg.org/snonux/gos/internal/table"
+)
+
+const downloadTimeout = 10 * time.Second
+
+// WithCard returns the preview with the card overrides of the entry front matter
+// applied, e.g. another title or image.
+func (p Preview) WithCard(ctx context.Context, args config.Args, card entry.Card) (Preview, error) {
+	if p.detected != nil {
+		p = *p.detected
+	}
+	detected := p
+	p.detected = &detected
+	p.none = card.None
+	if card.Title != "" {
+		p.title = card.Title
+	}
+	if card.Description != "" {
+		p.description = card.Description
+	}
+	switch {
+	case card.Image == "":
+	case strings.Contains(card.Image, "://"):
+		p.thumbnailURL = card.Image
+		newCtx, cancel := context.WithTimeout(ctx, downloadTimeout)
+		defer cancel()
+		var err error
+		if p.thumbnailDownloadPath, err = p.DownloadImage(newCtx, args); err != nil {
+			return p, err
+		}
+	default:
+		imagePath := card.Image
+		if !filepath.IsAbs(imagePath) {
+			imagePath = filepath.Join(args.GosDir, imagePath)
+		}
+		if _, err := os.Stat(imagePath); err != nil {
+			return p, err
+		}
+		p.thumbnailURL, p.thumbnailDownloadPath = card.Image, imagePath
+	}
+	return p, nil
+}
+
+// Option returns the prompt option to view and change the card of the entry.
+func (p *Preview) Option(ctx context.Context, args config.Args, en *entry.Entry) prompt.Option {
+	return prompt.Option{Key: "c", Description: "card", Run: func() error {
+		return p.Edit(ctx, args, en)
+	}}
+}
+
+// Edit shows the card and lets the user change it: pick another image of the
+// page, use a local image, change the title or description, or post without a
+// card. The changes are persisted in the front matter of the entry, so that they
+// survive retries.
+func (p *Preview) Edit(ctx context.Context, args config.Args, en *entry.Entry) error {
+	if p.url == "" {
+		colour.Infoln("The entry has got no link, so there is no card")
+		return nil
+	}
+	for {
+		p.render()
+		choice, err := prompt.Input("(t=title/s=description/i=image of the page/f=image file/n=no card/r=reset/b=back):")
+		if err != nil {
+			return err
+		}
+		card := en.FrontMatter.Card
+		switch choice {
+		case "t", "title":
+			if card.Title, err = prompt.Input("New title:"); err != nil {
+				return err
+			}
+		case "s", "description":
+			if card.Description, err = prompt.Input("New description:"); err != nil {
+				return err
+			}
+		case "i", "image":
+			if card.Image, err = p.chooseImage(); err != nil {
+				return err
+			}
+		case "f", "file":
+			if card.Image, err = prompt.Input("Image file path (relative to the gosDir or absolute):"); err != nil {
+				return err
+			}
+		case "n", "none":
+			card.None = !card.None
+		case "r", "reset":
+			card = entry.Card{}
+		case "b", "back", "":
+			return nil
+		default:
+			fmt.Println("Please respond with one of [tsifnrb].")
+			continue
+		}
+
+		updated, err := p.WithCard(ctx, args, card)
+		if err != nil {
+			colour.Errorln("Unable to use the card:", err)
+			continue
+		}
+		if err := en.SetCard(card); err != nil {
+			return err
+		}
+		*p = updated
+	}
+}
+
+func (p Preview) render() {
+	tab := table.New().Header("Card", "Value")
+	if p.none {
+		tab.Row("Card", "none, posting without a card")
+	} else {
+		tab.Row("Title", p.title).
+			Row("Description", p.description).
+			Row("URL", p.URL()).
+			Row("Image", p.thumbnailURL)
+	}
+	tab.MustRender()
+}
+
+// Lets the user choose one of the images found on the page.
+func (p Preview) chooseImage() (string, error) {
+	if len(p.images) == 0 {
+		colour.Infoln("No images found on the page")
+		return "", nil
+	}
+	tab := table.New().Header("#", "Image")
+	for i, image := range p.images {
+		tab.Row(i+1, image)
+	}
+	tab.MustRender()
+	for {
+		input, err := prompt.Input(fmt.Sprintf("Image number (1-%d):", len(p.images)))
+		if err != nil {
+			return "", err
+		}
+		if i, err := strconv.Atoi(input); err == nil && i >= 1 && i <= len(p.images) {
+			return p.images[i-1], nil
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"golang.org/x/net/html"
+)
+
+var (
+	errNoTitleElementFound = errors.New("no title element found")
+	errNoImageElementFound = errors.New("no image element found")
+)
+
+// The meta tags (by property or name) in order of preference.
+var (
+	titleMetaTags       = []string{"og:title", "twitter:title"}
+	descriptionMetaTags = []string{"og:description", "twitter:description", "description"}
+	imageMetaTags       = []string{"og:image:secure_url", "og:image", "og:image:url", "twitter:image", "twitter:image:src"}
+)
+
+// The parsed head of a page: the meta tags by property or name, the canonical
+// link and the title element.
+type page struct {
+	meta      map[string]string
+	canonical string
+	title     string
+	images    []string // All images of the page, in order of appearance
+}
+
+func parsePage(doc *html.Node) page {
+	p := page{meta: make(map[string]string)}
+	var traverse func(*html.Node)
+	traverse = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "meta":
+				key := strings.ToLower(attr(n, "property"))
+				if key == "" {
+					key = strings.ToLower(attr(n, "name"))
+				}
+				// The first occurrence wins, e.g. the first of several og:image tags.
+				if _, ok := p.meta[key]; key != "" && !ok {
+					p.meta[key] = strings.TrimSpace(attr(n, "content"))
+				}
+			case "link":
+				if p.canonical == "" && strings.EqualFold(attr(n, "rel"), "canonical") {
+					p.canonical = strings.TrimSpace(attr(n, "href"))
+				}
+			case "title":
+				if p.title == "" && n.FirstChild != nil {
+					p.title = strings.TrimSpace(n.FirstChild.Data)
+				}
+			case "img":
+				if src := attr(n, "src"); src != "" {
+					p.images = append(p.images, src)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			traverse(c)
+		}
+	}
+	traverse(doc)
+	return p
+}
+
+// Returns the first non-empty meta tag of the keys.
+func (p page) first(keys []string) string {
+	for _, key := range keys {
+		if value := p.meta[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func resolveURL(baseURL, rawURL string) (string, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse base URL: %w", err)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse raw URL: %w", err)
+	}
+	return base.ResolveReference(u).String(), nil
+}
+
+func extractFromURL(ctx context.Context, url string) (metadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return metadata{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return metadata{}, fmt.Errorf("failed to get URL: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			// Log the error but don't fail the operation since we've already read the data
+			colour.Errorln("Error closing response body:", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return metadata{}, fmt.Errorf("failed to get a successful response: %v", resp.StatusCode)
+	}
+
+	return extract(url, resp.Body)
+}
+
+// Extracts the metadata of the page. The OpenGraph and Twitter card meta tags are
+// preferred, falling back to the title element and the first image of the page.
+func extract(url string, htmlBody io.Reader) (metadata, error) {
+	doc, err := html.Parse(htmlBody)
+	if err != nil {
+		return metadata{}, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	p := parsePage(doc)
+
+	var (
+		errs error
+		md   = metadata{Description: p.first(descriptionMetaTags)}
+	)
+	if md.Title = p.first(titleMetaTags); md.Title == "" {
+		if md.Title = p.title; md.Title == "" {
+			errs = errors.Join(errs, errNoTitleElementFound)
+		}
+	}
+	if p.canonical != "" {
+		if md.CanonicalURL, err = resolveURL(url, p.canonical); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	// The card images first, as they are made for the preview.
+	var images []string
+	for _, key := range imageMetaTags {
+		if image := p.meta[key]; image != "" {
+			images = append(images, image)
+		}
+	}
+	for _, image := range append(images, p.images...) {
+		resolved, err := resolveURL(url, image)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		if !slices.Contains(md.Images, resolved) {
+			md.Images = append(md.Images, resolved)
+		}
+	}
+	if len(md.Images) == 0 {
+		errs = errors.Join(errs, errNoImageElementFound)
+	} else {
+		md.ImageURL = md.Images[0]
+	}
+	return md, errs
+}
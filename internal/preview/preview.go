@@ -0,0 +1,260 @@
+// Package preview fetches the link preview (title, description and image) of the
+// first link of an entry, for the platforms which don't generate it themselves.
+// The metadata and images are cached in the cache directory.
+package preview
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/oi"
+)
+
+// The sub directory of the cache directory holding the previews.
+const cacheSubDir = "previews"
+
+// Preview is the link preview of an URL.
+type Preview struct {
+	title, description, thumbnailURL, thumbnailDownloadPath, url, canonicalURL string
+
+	images   []string // All images found on the page
+	none     bool     // Post without a card
+	detected *Preview // The preview before applying the card overrides
+}
+
+// The cached metadata of an URL.
+type metadata struct {
+	Title        string
+	Description  string
+	ImageURL     string
+	Images       []string
+	CanonicalURL string
+	Fetched      time.Time
+}
+
+// New returns the preview of the first URL. Failing to fetch the metadata or the
+// image is not fatal, the preview then falls back to the URL as the title.
+func New(ctx context.Context, args config.Args, urls []string) (Preview, error) {
+	var p Preview
+	if len(urls) == 0 {
+		return p, nil
+	}
+	p.url = urls[0]
+
+	md, ok := readCache(args, p.url)
+	if !ok {
+		var err error
+		if md, err = extractFromURL(ctx, p.url); err != nil {
+			if errors.Is(err, errNoImageElementFound) {
+				colour.Infoln("URL", p.url, "is without any image, that's fine, though.")
+			}
+			if !errors.Is(err, errNoTitleElementFound) && !errors.Is(err, errNoImageElementFound) {
+				colour.Infoln("Skipping preview metadata for", p.url, "due to", err)
+				p.title = p.url
+				return p, nil
+			}
+		}
+		writeCache(args, p.url, md)
+	}
+	p.title, p.description, p.thumbnailURL, p.canonicalURL = md.Title, md.Description, md.ImageURL, md.CanonicalURL
+	p.images = md.Images
+	if p.title == "" {
+		colour.Infoln("Setting title to", p.url)
+		p.title = p.url
+	}
+
+	if p.thumbnailURL != "" {
+		var err error
+		if p.thumbnailDownloadPath, err = p.DownloadImage(ctx, args); err != nil {
+			colour.Infoln("Skipping preview image for", p.url, "due to", err)
+			p.thumbnailDownloadPath = ""
+			return p, nil
+		}
+		if p.thumbnailDownloadPath != "" {
+			colour.Infoln("Using preview image", p.thumbnailDownloadPath)
+		}
+	}
+	return p, nil
+}
+
+func (p Preview) String() string {
+	if p.none {
+		return "No card"
+	}
+	if p.thumbnailURL != "" {
+		return fmt.Sprintf("Title: %s; URL: %s, Image: %s", p.title, p.URL(), p.thumbnailURL)
+	}
+	return fmt.Sprintf("Title: %s; URL: %s", p.title, p.URL())
+}
+
+// URL returns the canonical URL of the page, if it has got one, otherwise the
+// URL linked. The query parameters of the URL linked are kept, so that e.g. the
+// utm_source of the link decoration still works.
+func (p Preview) URL() string {
+	if p.canonicalURL == "" {
+		return p.url
+	}
+	canonical, err := url.Parse(p.canonicalURL)
+	if err != nil || (canonical.Scheme != "https" && canonical.Scheme != "http") {
+		return p.url
+	}
+	linked, err := url.Parse(p.url)
+	if err != nil {
+		return p.url
+	}
+	query := canonical.Query()
+	for key, values := range linked.Query() {
+		query[key] = values
+	}
+	canonical.RawQuery = query.Encode()
+	return canonical.String()
+}
+
+func (p Preview) TitleAndURL() (string, string, bool) {
+	return p.title, p.URL(), !p.none && p.url != "" && p.title != ""
+}
+
+// Description returns the description of the page, if any.
+func (p Preview) Description() (string, bool) {
+	return p.description, !p.none && p.description != ""
+}
+
+func (p Preview) Thumbnail() (string, bool) {
+	return p.thumbnailDownloadPath, !p.none && p.thumbnailDownloadPath != ""
+}
+
+// DownloadImage downloads the preview image into the cache directory, unless it
+// is cached already, and returns its path.
+func (p Preview) DownloadImage(ctx context.Context, args config.Args) (string, error) {
+	// Skip data URIs - they can't be downloaded and don't provide meaningful images
+	if u, err := url.Parse(p.thumbnailURL); err == nil && u.Scheme == "data" {
+		colour.Infoln("Skipping data URI image, using article metadata instead")
+		return "", nil
+	}
+	return DownloadImage(ctx, args, p.thumbnailURL)
+}
+
+// DownloadImage downloads the image into the cache directory, unless it is cached
+// already, and returns its path. E.g. for images of entries linked by URL.
+func DownloadImage(ctx context.Context, args config.Args, imageURL string) (string, error) {
+	destFile := imageCachePath(args, imageURL)
+	if fresh(args, destFile) {
+		return destFile, nil
+	}
+	if err := oi.EnsureParentDir(destFile); err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			// Log the error but don't fail the operation since we've already read the data
+			colour.Errorln("Error closing response body:", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("bad status while trying to download image: %s", resp.Status)
+	}
+
+	// Download into a temp file first, so that a failed download doesn't leave a
+	// truncated image in the cache, which would be fresh on the next run.
+	tmpFile := destFile + ".tmp"
+	out, err := os.Create(tmpFile)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", tmpFile, err)
+	}
+	_, err = io.Copy(out, resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile, destFile)
+	}
+	if err != nil {
+		if err := os.Remove(tmpFile); err != nil && !os.IsNotExist(err) {
+			colour.Errorln("Error removing", tmpFile, ":", err)
+		}
+		return "", err
+	}
+	return destFile, nil
+}
+
+func cacheKey(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return hex.EncodeToString(sum[:])
+}
+
+func metadataCachePath(args config.Args, rawURL string) string {
+	return filepath.Join(args.CacheDir, cacheSubDir, cacheKey(rawURL)+".json")
+}
+
+// The images keep their extension, as e.g. LinkedIn needs it to upload them.
+func imageCachePath(args config.Args, imageURL string) string {
+	var ext string
+	if u, err := url.Parse(imageURL); err == nil {
+		ext = path.Ext(u.Path)
+	}
+	return filepath.Join(args.CacheDir, cacheSubDir, "images", cacheKey(imageURL)+ext)
+}
+
+// Whether the cached file exists and is younger than the preview TTL.
+func fresh(args config.Args, filePath string) bool {
+	info, err := os.Stat(filePath)
+	return err == nil && time.Since(info.ModTime()) < args.PreviewTTL
+}
+
+func readCache(args config.Args, rawURL string) (metadata, bool) {
+	var md metadata
+	if args.CacheDir == "" {
+		return md, false
+	}
+	data, err := os.ReadFile(metadataCachePath(args, rawURL))
+	if err != nil {
+		return md, false
+	}
+	if err := json.Unmarshal(data, &md); err != nil {
+		return md, false
+	}
+	return md, time.Since(md.Fetched) < args.PreviewTTL
+}
+
+// Caching is best effort, a failure only means fetching the metadata again.
+func writeCache(args config.Args, rawURL string, md metadata) {
+	if args.CacheDir == "" || args.PreviewTTL <= 0 {
+		return
+	}
+	md.Fetched = time.Now()
+	data, err := json.MarshalIndent(md, "", "  ")
+	if err != nil {
+		colour.Infoln("Unable to cache preview of", rawURL, err)
+		return
+	}
+	cachePath := metadataCachePath(args, rawURL)
+	if err := oi.EnsureParentDir(cachePath); err != nil {
+		colour.Infoln("Unable to cache preview of", rawURL, err)
+		return
+	}
+	if err := oi.WriteFile(cachePath, string(data)); err != nil {
+		colour.Infoln("Unable to cache preview of", rawURL, err)
+	}
+}
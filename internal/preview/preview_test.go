@@ -0,0 +1,297 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/entry"
+)
+
+func TestPreviewExtract(t *testing.T) {
+	var (
+		expectedTitle    = "Baz"
+		expectedImageURL = "https://free.beer:666/bar/foo.jpg"
+		mockHTML         = strings.NewReader(`
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Baz</title>
+</head>
+<body>
+    <img src="./foo.jpg" alt="Foo">
+</body>
+</html>
+`)
+	)
+
+	md, err := extract("https://free.beer:666/bar/", mockHTML)
+	if err != nil {
+		t.Error(err)
+	}
+	if md.Title != expectedTitle {
+		t.Errorf("expected title '%s' but got '%s'", expectedTitle, md.Title)
+	}
+	if md.ImageURL != expectedImageURL {
+		t.Errorf("expected imageURL '%s' but got '%s'", expectedImageURL, md.ImageURL)
+	}
+}
+
+func TestNewPreviewIgnoresForbiddenPage(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "blocked", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	prev, err := New(context.Background(), config.Args{
+		CacheDir:   t.TempDir(),
+		PreviewTTL: time.Hour,
+	}, []string{srv.URL})
+	if err != nil {
+		t.Fatalf("expected preview fetch failure to be non-fatal, got %v", err)
+	}
+
+	title, sourceURL, ok := prev.TitleAndURL()
+	if !ok {
+		t.Fatal("expected preview to keep URL fallback")
+	}
+	if title != srv.URL {
+		t.Fatalf("expected fallback title %q, got %q", srv.URL, title)
+	}
+	if sourceURL != srv.URL {
+		t.Fatalf("expected source URL %q, got %q", srv.URL, sourceURL)
+	}
+	if _, ok := prev.Thumbnail(); ok {
+		t.Fatal("expected no thumbnail for forbidden preview page")
+	}
+}
+
+func TestNewPreviewIgnoresForbiddenImage(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte(`
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Blocked image</title>
+</head>
+<body>
+    <img src="/blocked.jpg" alt="blocked">
+</body>
+</html>
+`))
+		case "/blocked.jpg":
+			http.Error(w, "blocked", http.StatusForbidden)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cacheDir := t.TempDir()
+	prev, err := New(context.Background(), config.Args{
+		CacheDir:   cacheDir,
+		PreviewTTL: time.Hour,
+	}, []string{srv.URL})
+	if err != nil {
+		t.Fatalf("expected image download failure to be non-fatal, got %v", err)
+	}
+
+	title, sourceURL, ok := prev.TitleAndURL()
+	if !ok {
+		t.Fatal("expected preview title and URL to be preserved")
+	}
+	if title != "Blocked image" {
+		t.Fatalf("expected title %q, got %q", "Blocked image", title)
+	}
+	if sourceURL != srv.URL {
+		t.Fatalf("expected source URL %q, got %q", srv.URL, sourceURL)
+	}
+	if thumbnailPath, ok := prev.Thumbnail(); ok {
+		t.Fatalf("expected no thumbnail after blocked download, got %q", thumbnailPath)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(cacheDir, cacheSubDir, "images", "*"))
+	if err != nil {
+		t.Fatalf("glob failed: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Fatalf("expected no cached images, got %v", matches)
+	}
+}
+
+func TestPreviewExtractOpenGraph(t *testing.T) {
+	mockHTML := strings.NewReader(`
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Baz | Site</title>
+    <meta property="og:title" content="Baz">
+    <meta property="og:description" content="All about baz">
+    <meta name="twitter:image" content="https://cdn.free.beer/twitter.jpg">
+    <meta property="og:image" content="/og.jpg">
+    <link rel="canonical" href="/bar/baz.html">
+</head>
+<body>
+    <img src="/logo.png" alt="Logo">
+</body>
+</html>
+`)
+	md, err := extract("https://free.beer/bar/baz.html?utm_source=rss", mockHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := metadata{
+		Title:        "Baz",
+		Description:  "All about baz",
+		ImageURL:     "https://free.beer/og.jpg",
+		Images:       []string{"https://free.beer/og.jpg", "https://cdn.free.beer/twitter.jpg", "https://free.beer/logo.png"},
+		CanonicalURL: "https://free.beer/bar/baz.html",
+	}
+	if !reflect.DeepEqual(md, expected) {
+		t.Errorf("expected %+v but got %+v", expected, md)
+	}
+
+	// The twitter card image is the fallback of the OpenGraph one.
+	md, err = extract("https://free.beer/", strings.NewReader(
+		`<html><head><meta name="twitter:image" content="https://cdn.free.beer/twitter.jpg"></head></html>`))
+	if !errors.Is(err, errNoTitleElementFound) {
+		t.Errorf("expected no title error but got %v", err)
+	}
+	if md.ImageURL != "https://cdn.free.beer/twitter.jpg" {
+		t.Errorf("expected the twitter image but got %s", md.ImageURL)
+	}
+}
+
+func TestNewPreviewCache(t *testing.T) {
+	t.Parallel()
+
+	var pageRequests, imageRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/post.html":
+			pageRequests.Add(1)
+			_, _ = w.Write([]byte(`<html><head>
+<meta property="og:title" content="Post">
+<meta property="og:image" content="/image.jpg">
+<link rel="canonical" href="/post.html">
+</head></html>`))
+		case "/image.jpg":
+			imageRequests.Add(1)
+			_, _ = w.Write([]byte("jpg"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	args := config.Args{CacheDir: t.TempDir(), PreviewTTL: time.Hour}
+	linked := srv.URL + "/post.html?utm_source=linkedin"
+	for range 2 {
+		prev, err := New(context.Background(), args, []string{linked})
+		if err != nil {
+			t.Fatal(err)
+		}
+		title, sourceURL, _ := prev.TitleAndURL()
+		if title != "Post" || sourceURL != linked {
+			t.Errorf("unexpected title %q and URL %q", title, sourceURL)
+		}
+		if thumbnailPath, ok := prev.Thumbnail(); !ok || filepath.Ext(thumbnailPath) != ".jpg" {
+			t.Errorf("expected a cached jpg thumbnail, got %q", thumbnailPath)
+		}
+	}
+	if pageRequests.Load() != 1 || imageRequests.Load() != 1 {
+		t.Errorf("expected one request each, got %d page and %d image requests", pageRequests.Load(), imageRequests.Load())
+	}
+
+	// Expired cache entries are fetched again.
+	args.PreviewTTL = 0
+	if _, err := New(context.Background(), args, []string{linked}); err != nil {
+		t.Fatal(err)
+	}
+	if pageRequests.Load() != 2 || imageRequests.Load() != 2 {
+		t.Errorf("expected two requests each, got %d page and %d image requests", pageRequests.Load(), imageRequests.Load())
+	}
+}
+
+func TestPreviewWithCard(t *testing.T) {
+	gosDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(gosDir, "card.png"), []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	args := config.Args{GosDir: gosDir, CacheDir: t.TempDir(), PreviewTTL: time.Hour}
+	detected := Preview{title: "Detected", description: "About", url: "https://foo.zone/post.html"}
+
+	prev, err := detected.WithCard(context.Background(), args, entry.Card{Title: "Better", Image: "card.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title, _, ok := prev.TitleAndURL(); !ok || title != "Better" {
+		t.Errorf("expected the title of the card but got %q", title)
+	}
+	if description, _ := prev.Description(); description != "About" {
+		t.Errorf("expected the detected description but got %q", description)
+	}
+	if thumbnailPath, ok := prev.Thumbnail(); !ok || thumbnailPath != filepath.Join(gosDir, "card.png") {
+		t.Errorf("expected the local image but got %q", thumbnailPath)
+	}
+
+	// Another card replaces the previous one instead of adding to it.
+	if prev, err = prev.WithCard(context.Background(), args, entry.Card{None: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := prev.TitleAndURL(); ok {
+		t.Error("expected no card")
+	}
+	if prev, err = prev.WithCard(context.Background(), args, entry.Card{}); err != nil {
+		t.Fatal(err)
+	}
+	if title, _, ok := prev.TitleAndURL(); !ok || title != "Detected" {
+		t.Errorf("expected the detected title but got %q", title)
+	}
+	if _, ok := prev.Thumbnail(); ok {
+		t.Error("expected no thumbnail")
+	}
+
+	if _, err := detected.WithCard(context.Background(), args, entry.Card{Image: "missing.png"}); err == nil {
+		t.Error("expected an error for a missing image file")
+	}
+}
+
+func TestDownloadImageTruncated(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The connection is closed before the announced length was sent.
+		w.Header().Set("Content-Length", "100")
+		_, _ = w.Write([]byte("jpg"))
+	}))
+	defer srv.Close()
+
+	args := config.Args{CacheDir: t.TempDir(), PreviewTTL: time.Hour}
+	imageURL := srv.URL + "/image.jpg"
+	if _, err := DownloadImage(context.Background(), args, imageURL); err == nil {
+		t.Fatal("expected an error for a truncated download")
+	}
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(imageCachePath(args, imageURL)), "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("expected no (partial) image in the cache but got %v", matches)
+	}
+}
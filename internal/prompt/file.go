@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 
 	"codeberg.org/snonux/gos/internal/colour"
@@ -14,13 +15,29 @@ import (
 )
 
 var (
-	ErrAborted     = errors.New("aborted")
+	ErrAborted = errors.New("aborted")
+	// ErrDeleted is returned when the file is to be deleted. The prompt doesn't
+	// delete it, the caller moves it into the trashbin.
 	ErrDeleted     = errors.New("deleted")
 	ErrRamdomOther = errors.New("randomOther")
 	RandomOption   = true
 )
 
+// Option is an additional answer of the file action prompt, e.g. to change the
+// link card of a post. The prompt is shown again after running it.
+type Option struct {
+	Key         string
+	Description string
+	Run         func() error
+}
+
 func FileAction(question, content, filePath string, includeRandomOption ...bool) (string, error) {
+	includeRandom := len(includeRandomOption) > 0 && includeRandomOption[0] == RandomOption
+	return FileActionWith(question, content, filePath, includeRandom)
+}
+
+// FileActionWith is FileAction with additional options.
+func FileActionWith(question, content, filePath string, includeRandom bool, options ...Option) (string, error) {
 	table.New().
 		WithBaseColor(colour.AttentionCol).
 		WithHeaderColor(colour.AckCol).
@@ -29,21 +46,31 @@ func FileAction(question, content, filePath string, includeRandomOption ...bool)
 		MustRender()
 	reader := bufio.NewReader(os.Stdin)
 
-	includeRandom := len(includeRandomOption) > 0 && includeRandomOption[0] == RandomOption
-	var randomOption string
+	var extra, extraKeys string
 	if includeRandom {
-		randomOption = "/r=random other"
+		extra, extraKeys = "/r=random other", "r"
+	}
+	for _, option := range options {
+		extra += fmt.Sprintf("/%s=%s", option.Key, option.Description)
+		extraKeys += option.Key
 	}
 
 	for {
 		fmt.Print("  ")
-		colour.Ackf("(y=yes/n=no/e=edit/d=delete%s):", randomOption)
+		colour.Ackf("(y=yes/n=no/e=edit/d=delete%s):", extra)
 		input, err := reader.ReadString('\n')
 		if err != nil {
 			return "", fmt.Errorf("error reading input: %w", err)
 		}
+		input = strings.ToLower(strings.TrimSpace(input))
 
-		switch strings.ToLower(strings.TrimSpace(input)) {
+		if i := slices.IndexFunc(options, func(o Option) bool { return o.Key == input }); i >= 0 {
+			if err := options[i].Run(); err != nil {
+				return content, err
+			}
+			return FileActionWith(question, content, filePath, includeRandom, options...)
+		}
+		switch input {
 		case "y", "yes":
 			return content, nil
 		case "n", "no":
@@ -55,11 +82,8 @@ func FileAction(question, content, filePath string, includeRandomOption ...bool)
 			if content, err = oi.SlurpAndTrim(filePath); err != nil {
 				return content, err
 			}
-			return FileAction(question, content, filePath, includeRandomOption...)
+			return FileActionWith(question, content, filePath, includeRandom, options...)
 		case "d", "delete":
-			if err := os.Remove(filePath); err != nil {
-				return content, err
-			}
 			return content, fmt.Errorf("%w %s", ErrDeleted, filePath)
 		case "r", "random", "random other":
 			if includeRandom {
@@ -67,11 +91,7 @@ func FileAction(question, content, filePath string, includeRandomOption ...bool)
 			}
 			fallthrough
 		default:
-			var r string
-			if includeRandom {
-				r = "r"
-			}
-			fmt.Printf("Please respond with one of [yned%s].\n", r)
+			fmt.Printf("Please respond with one of [yned%s].\n", extraKeys)
 		}
 	}
 }
@@ -2,11 +2,14 @@ package prompt
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"codeberg.org/snonux/gos/internal/colour"
 	"codeberg.org/snonux/gos/internal/table"
+	"golang.org/x/term"
 )
 
 func Acknowledge(messages ...string) error {
@@ -28,3 +31,32 @@ func Acknowledge(messages ...string) error {
 	}
 	return nil
 }
+
+// Input asks for a line of input, e.g. a new title, and returns it trimmed.
+func Input(question string) (string, error) {
+	fmt.Printf("  ")
+	colour.Ackf("%s ", question)
+	reader := bufio.NewReader(os.Stdin)
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("error reading input: %w", err)
+	}
+	return strings.TrimSpace(input), nil
+}
+
+// Password asks for a password without echoing it. It fails if stdin isn't a
+// terminal.
+func Password(question string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("unable to ask for a password, stdin is not a terminal")
+	}
+	fmt.Printf("  ")
+	colour.Ackf("%s ", question)
+	password, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return nil, fmt.Errorf("error reading password: %w", err)
+	}
+	return password, nil
+}
@@ -0,0 +1,362 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/entry"
+	"codeberg.org/snonux/gos/internal/journal"
+	"codeberg.org/snonux/gos/internal/markdown"
+	"codeberg.org/snonux/gos/internal/oi"
+	"codeberg.org/snonux/gos/internal/platforms"
+	"codeberg.org/snonux/gos/internal/timestamp"
+	"codeberg.org/snonux/gos/internal/trash"
+)
+
+var errAmbiguous = errors.New("ambiguous entry name")
+
+// Retag adds and removes tags on all copies of the named entry, e.g. in the inbox
+// and in all ./db/platforms/PLATFORM directories.
+func Retag(args config.Args, name string, add, remove []string) error {
+	for i, tag := range add {
+		if !strings.HasPrefix(tag, "share:") {
+			continue
+		}
+		var err error
+		if add[i], err = platforms.ExpandAliases(tag); err != nil {
+			return err
+		}
+	}
+
+	entries, err := findEntries(args.GosDir, name)
+	if err != nil {
+		return err
+	}
+	for _, en := range entries {
+		if err := retag(args, en, add, remove); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Retags the entry in its front matter, if it has got one (e.g. after gos migrate),
+// otherwise in its file name. Share tags left in the file name are removed when a
+// share tag is added to the front matter, so that they don't conflict.
+func retag(args config.Args, en entry.Entry, add, remove []string) error {
+	content, err := oi.SlurpAndTrim(en.Path)
+	if err != nil {
+		return err
+	}
+	if _, delim, _ := markdown.SplitFrontMatter(content); delim == "" {
+		newPath, err := en.Retag(add, remove)
+		if err != nil {
+			return err
+		}
+		return rename(args, en.Path, newPath)
+	}
+
+	newContent, err := entry.RetagFrontMatter(content, add, remove)
+	if err != nil {
+		return fmt.Errorf("%s: %w", en.Path, err)
+	}
+	fileNameTags := remove
+	if slices.ContainsFunc(add, func(tag string) bool { return strings.HasPrefix(tag, "share:") }) {
+		fileNameTags = append(slices.Clone(remove), "share")
+	}
+	newPath, err := en.Retag(nil, fileNameTags)
+	if err != nil {
+		return err
+	}
+	if newPath == en.Path && newContent == content+"\n" {
+		return nil
+	}
+	if newPath != en.Path {
+		if _, err := os.Stat(newPath); err == nil {
+			return fmt.Errorf("unable to retag %s as %s already exists", en.Path, newPath)
+		}
+	}
+	if args.DryRun {
+		colour.Infoln("Not retagging", en.Path, "as dry-run mode enabled")
+		return nil
+	}
+
+	colour.Infofln("Retagging %s -> %s", en.Path, newPath)
+	if err := oi.WriteFile(newPath, newContent); err != nil {
+		return err
+	}
+	if newPath != en.Path {
+		if err := os.Remove(en.Path); err != nil {
+			return err
+		}
+	}
+	return journal.Record(journal.Op{Kind: journal.Retag, From: en.Path, To: newPath, Content: content})
+}
+
+// Reprioritise replaces the priority tag (now, prio or soon) of the named entry.
+// The priority "none" removes any priority tag.
+func Reprioritise(args config.Args, name, prio string) error {
+	if prio == "none" {
+		return Retag(args, name, nil, entry.PrioTags)
+	}
+	if !slices.Contains(entry.PrioTags, prio) {
+		return fmt.Errorf("invalid priority '%s', expected one of %v or none", prio, entry.PrioTags)
+	}
+	var remove []string
+	for _, tag := range entry.PrioTags {
+		if tag != prio {
+			remove = append(remove, tag)
+		}
+	}
+	return Retag(args, name, []string{prio}, remove)
+}
+
+// Requeue moves the posted copies of the named entry back to queued, so that they
+// will be posted again. If no platforms are given, all posted copies are requeued.
+func Requeue(args config.Args, name string, platformStrs []string) error {
+	entries, err := platformEntries(args.GosDir, name, entry.Posted, platformStrs)
+	if err != nil {
+		return err
+	}
+	for _, en := range entries {
+		queuedPath, err := timestamp.UpdateInFilename(strings.TrimSuffix(en.Path, ".posted")+".queued", -2)
+		if err != nil {
+			return err
+		}
+		if err := rename(args, en.Path, queuedPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unqueue removes the queued copies of the named entry from the given platforms
+// only. The copies are moved to the trashbin.
+func Unqueue(args config.Args, name string, platformStrs []string) error {
+	if len(platformStrs) == 0 {
+		return errors.New("no platform specified to unqueue from")
+	}
+	entries, err := platformEntries(args.GosDir, name, entry.Queued, platformStrs)
+	if err != nil {
+		return err
+	}
+	for _, en := range entries {
+		if err := moveToTrash(args, en.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Inbox pulls the queued copies of the named entry back into the inbox (the
+// gosDir), so that it can be edited. It will be queued again on the next run.
+func Inbox(args config.Args, name string) error {
+	entries, err := platformEntries(args.GosDir, name, entry.Queued, nil)
+	if err != nil {
+		return err
+	}
+	inboxPath := filepath.Join(args.GosDir, entries[0].Name())
+	if _, err := os.Stat(inboxPath); err == nil {
+		return fmt.Errorf("%s already exists in the inbox", inboxPath)
+	}
+
+	colour.Infoln("Pulling", entries[0].Path, "->", inboxPath)
+	if !args.DryRun {
+		if err := oi.CopyFile(entries[0].Path, inboxPath); err != nil {
+			return err
+		}
+		if err := journal.Record(journal.Op{Kind: journal.Copy, From: entries[0].Path, To: inboxPath}); err != nil {
+			return err
+		}
+	}
+	for _, en := range entries {
+		if err := moveToTrash(args, en.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Migrate rewrites the file name tags of all inboxed and queued entries into
+// their front matter, e.g. foo.prio.share:mastodon.txt becomes foo.txt with the
+// tags prio and the share include list mastodon in the front matter.
+func Migrate(args config.Args) error {
+	dbDir := filepath.Join(args.GosDir, "db")
+	dirs := []string{args.GosDir, dbDir}
+	platformDirs, err := filepath.Glob(filepath.Join(dbDir, "platforms", "*"))
+	if err != nil {
+		return err
+	}
+	dirs = append(dirs, platformDirs...)
+
+	for _, dir := range dirs {
+		suffixes := []string{".queued"}
+		if dir == args.GosDir {
+			suffixes = validExtensions
+		}
+		paths, err := oi.ReadDir(dir, find(dir, suffixes...))
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			if err := migrate(args, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func migrate(args config.Args, filePath string) error {
+	en, err := entry.New(filePath)
+	if err != nil {
+		return err
+	}
+	content, err := oi.SlurpAndTrim(filePath)
+	if err != nil {
+		return err
+	}
+	newPath, newContent, err := en.MigrateFrontMatter(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	if newPath == filePath {
+		return nil
+	}
+	if _, err := os.Stat(newPath); err == nil {
+		return fmt.Errorf("unable to migrate %s as %s already exists", filePath, newPath)
+	}
+	if args.DryRun {
+		colour.Infoln("Not migrating", filePath, "to", newPath, "as dry-run mode enabled")
+		return nil
+	}
+
+	colour.Infofln("Migrating %s -> %s", filePath, newPath)
+	if err := oi.WriteFile(newPath, newContent); err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil {
+		return err
+	}
+	return journal.Record(journal.Op{
+		Kind: journal.Migrate, From: filePath, To: newPath, Content: content,
+	})
+}
+
+// platformEntries returns all copies of the named entry in the given state in the
+// ./db/platforms/PLATFORM directories. No platforms mean all platforms.
+func platformEntries(gosDir, name string, state entry.State, platformStrs []string) ([]entry.Entry, error) {
+	var wanted []string
+	for _, platformStr := range platformStrs {
+		platform, err := platforms.New(platformStr)
+		if err != nil {
+			return nil, err
+		}
+		wanted = append(wanted, platform.String())
+	}
+
+	entries, err := findEntries(gosDir, name)
+	if err != nil {
+		return nil, err
+	}
+	var results []entry.Entry
+	for _, en := range entries {
+		platformStr := filepath.Base(filepath.Dir(en.Path))
+		if filepath.Dir(filepath.Dir(en.Path)) != filepath.Join(gosDir, "db", "platforms") {
+			continue
+		}
+		if en.State != state || (len(wanted) > 0 && !slices.Contains(wanted, platformStr)) {
+			continue
+		}
+		results = append(results, en)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%w: no %s entry '%s'", oi.ErrNotFound, state, name)
+	}
+	return results, nil
+}
+
+// findEntries returns all copies of the named entry in the inbox, in ./db and in
+// all ./db/platforms/PLATFORM directories.
+func findEntries(gosDir, name string) ([]entry.Entry, error) {
+	dbDir := filepath.Join(gosDir, "db")
+	dirs := []string{gosDir, dbDir}
+	platformDirs, err := filepath.Glob(filepath.Join(dbDir, "platforms", "*"))
+	if err != nil {
+		return nil, err
+	}
+	dirs = append(dirs, platformDirs...)
+
+	var (
+		entries []entry.Entry
+		names   = make(map[string]struct{})
+	)
+	for _, dir := range dirs {
+		suffixes := []string{".queued", ".posted"}
+		if dir == gosDir {
+			suffixes = validExtensions
+		}
+		paths, err := oi.ReadDir(dir, find(dir, suffixes...))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			en, err := entry.New(path)
+			if err != nil {
+				colour.Infoln(err)
+				continue
+			}
+			if en.Matches(name) {
+				entries = append(entries, en)
+				names[en.Name()] = struct{}{}
+			}
+		}
+	}
+
+	switch {
+	case len(entries) == 0:
+		return nil, fmt.Errorf("%w: '%s'", oi.ErrNotFound, name)
+	case len(names) > 1:
+		return nil, fmt.Errorf("%w '%s', matches: %v", errAmbiguous, name, mapKeys(names))
+	}
+	return entries, nil
+}
+
+func rename(args config.Args, srcPath, dstPath string) error {
+	if srcPath == dstPath {
+		return nil
+	}
+	if args.DryRun {
+		colour.Infoln("Not renaming", srcPath, "to", dstPath, "as dry-run mode enabled")
+		return nil
+	}
+	colour.Infofln("Renaming %s -> %s", srcPath, dstPath)
+	if err := oi.Rename(srcPath, dstPath); err != nil {
+		return err
+	}
+	return journal.Record(journal.Op{Kind: journal.Rename, From: srcPath, To: dstPath})
+}
+
+func moveToTrash(args config.Args, filePath string) error {
+	if args.DryRun {
+		colour.Infoln("Not trashing", filePath, "as dry-run mode enabled")
+		return nil
+	}
+	_, err := trash.New(args).Move(filePath)
+	return err
+}
+
+func mapKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
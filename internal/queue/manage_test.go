@@ -0,0 +1,180 @@
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/entry"
+)
+
+func newTestGosDir(t *testing.T, files ...string) config.Args {
+	t.Helper()
+	gosDir := t.TempDir()
+	for _, file := range files {
+		filePath := filepath.Join(gosDir, file)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte("Hello world #foo"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return config.Args{GosDir: gosDir}
+}
+
+func expectFiles(t *testing.T, gosDir string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		if _, err := os.Stat(filepath.Join(gosDir, file)); err != nil {
+			t.Errorf("expected %s to exist: %v", file, err)
+		}
+	}
+}
+
+func TestRetag(t *testing.T) {
+	args := newTestGosDir(t,
+		"db/platforms/mastodon/foo.ask.txt.20250101-010101.queued",
+		"db/platforms/linkedin/foo.ask.txt.20250101-010101.posted",
+		"db/platforms/linkedin/bar.txt.20250101-010101.queued",
+	)
+	if err := Retag(args, "foo", []string{"prio", "share:ma"}, []string{"ask"}); err != nil {
+		t.Fatal(err)
+	}
+	expectFiles(t, args.GosDir,
+		"db/platforms/mastodon/foo.prio.share:mastodon.txt.20250101-010101.queued",
+		"db/platforms/linkedin/foo.prio.share:mastodon.txt.20250101-010101.posted",
+		"db/platforms/linkedin/bar.txt.20250101-010101.queued",
+	)
+
+	if err := Reprioritise(args, "foo.txt", "now"); err != nil {
+		t.Fatal(err)
+	}
+	expectFiles(t, args.GosDir,
+		"db/platforms/mastodon/foo.share:mastodon.now.txt.20250101-010101.queued",
+	)
+}
+
+func TestRetagAfterMigrate(t *testing.T) {
+	args := newTestGosDir(t,
+		"db/platforms/mastodon/foo.prio.share:mastodon.txt.20250101-010101.queued",
+		"db/platforms/mastodon/bar.share:mastodon.txt.20250101-010101.queued",
+	)
+	if err := Migrate(args); err != nil {
+		t.Fatal(err)
+	}
+	fooPath := filepath.Join(args.GosDir, "db/platforms/mastodon/foo.txt.20250101-010101.queued")
+	barPath := filepath.Join(args.GosDir, "db/platforms/mastodon/bar.txt.20250101-010101.queued")
+	if err := Reprioritise(args, "foo", "none"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Retag(args, "foo", []string{"ask", "share:linkedin"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := Retag(args, "bar", nil, []string{"share"}); err != nil {
+		t.Fatal(err)
+	}
+
+	en, err := entry.New(fooPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if en.HasTag("prio") || !en.HasTag("ask") || !en.HasTag("share:linkedin") || en.HasTag("share:mastodon") {
+		t.Errorf("expected the tags ask and share:linkedin in the front matter but got %v", en.Tags)
+	}
+	if en, err = entry.New(barPath); err != nil {
+		t.Fatal(err)
+	}
+	if len(en.Tags) != 0 {
+		t.Errorf("expected no tags but got %v", en.Tags)
+	}
+	content, _, err := en.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "Hello world #foo" {
+		t.Errorf("unexpected content '%s'", content)
+	}
+}
+
+func TestRetagAmbiguous(t *testing.T) {
+	args := newTestGosDir(t,
+		"db/platforms/mastodon/foo.txt.20250101-010101.queued",
+		"db/platforms/mastodon/foo.md.20250101-010101.queued",
+	)
+	if err := Retag(args, "foo", []string{"prio"}, nil); err == nil {
+		t.Error("expected an error for an ambiguous entry name")
+	}
+}
+
+func TestUnqueueAndInbox(t *testing.T) {
+	args := newTestGosDir(t,
+		"db/platforms/mastodon/foo.txt.20250101-010101.queued",
+		"db/platforms/linkedin/foo.txt.20250101-010101.queued",
+		"db/platforms/noop/foo.txt.20250101-010101.posted",
+	)
+	if err := Unqueue(args, "foo", []string{"ma"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(args.GosDir, "db/platforms/mastodon/foo.txt.20250101-010101.queued")); err == nil {
+		t.Error("expected entry to be unqueued from mastodon")
+	}
+	expectFiles(t, args.GosDir, "db/platforms/linkedin/foo.txt.20250101-010101.queued")
+
+	if err := Inbox(args, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	expectFiles(t, args.GosDir, "foo.txt", "db/platforms/noop/foo.txt.20250101-010101.posted")
+	if _, err := os.Stat(filepath.Join(args.GosDir, "db/platforms/linkedin/foo.txt.20250101-010101.queued")); err == nil {
+		t.Error("expected entry to be pulled back from linkedin")
+	}
+}
+
+func TestRequeue(t *testing.T) {
+	args := newTestGosDir(t, "db/platforms/mastodon/foo.txt.20250101-010101.posted")
+	if err := Requeue(args, "foo", nil); err != nil {
+		t.Fatal(err)
+	}
+	queued, err := filepath.Glob(filepath.Join(args.GosDir, "db/platforms/mastodon/foo.txt.*.queued"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 1 {
+		t.Errorf("expected one requeued entry but got %v", queued)
+	}
+	if err := Requeue(args, "foo", nil); err == nil {
+		t.Error("expected an error as there is no posted entry left")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	args := newTestGosDir(t,
+		"foo.prio.txt",
+		"db/platforms/mastodon/bar.share:mastodon.txt.20250101-010101.queued",
+		"db/platforms/mastodon/baz.txt.20250101-010101.posted",
+	)
+	if err := Migrate(args); err != nil {
+		t.Fatal(err)
+	}
+	expectFiles(t, args.GosDir,
+		"foo.txt",
+		"db/platforms/mastodon/bar.txt.20250101-010101.queued",
+		"db/platforms/mastodon/baz.txt.20250101-010101.posted",
+	)
+
+	en, err := entry.New(filepath.Join(args.GosDir, "db/platforms/mastodon/bar.txt.20250101-010101.queued"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !en.HasTag("share:mastodon") {
+		t.Errorf("expected share tag from the front matter but got %v", en.Tags)
+	}
+	content, _, err := en.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "Hello world #foo" {
+		t.Errorf("unexpected content '%s'", content)
+	}
+}
@@ -1,6 +1,8 @@
 package queue
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,18 +11,26 @@ import (
 
 	"codeberg.org/snonux/gos/internal/colour"
 	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/dedup"
+	"codeberg.org/snonux/gos/internal/document"
 	"codeberg.org/snonux/gos/internal/entry"
+	"codeberg.org/snonux/gos/internal/hashtag"
+	"codeberg.org/snonux/gos/internal/journal"
+	"codeberg.org/snonux/gos/internal/lint"
 	"codeberg.org/snonux/gos/internal/oi"
 	"codeberg.org/snonux/gos/internal/platforms"
+	"codeberg.org/snonux/gos/internal/prompt"
 	"codeberg.org/snonux/gos/internal/tags"
+	"codeberg.org/snonux/gos/internal/textlen"
 	"codeberg.org/snonux/gos/internal/timestamp"
+	"codeberg.org/snonux/gos/internal/trash"
 )
 
 // Strictly, we only operate on .txt files, but we also accept .md as Obsidian creates only .md files.
 var validExtensions = []string{".txt", ".md"}
 
-func Run(args config.Args) error {
-	if err := queueEntries(args); err != nil {
+func Run(ctx context.Context, args config.Args) error {
+	if err := queueEntries(ctx, args); err != nil {
 		return err
 	}
 	if err := queueEntriesToPlatforms(args); err != nil {
@@ -30,45 +40,280 @@ func Run(args config.Args) error {
 }
 
 // Queue all *.txt into ./db/*.txt.STAMP.queued
-func queueEntries(args config.Args) error {
+func queueEntries(ctx context.Context, args config.Args) error {
 	ch, err := oi.ReadDirCh(args.GosDir, find(args.GosDir, validExtensions...))
 	if err != nil {
 		return err
 	}
 
+	run := &checks{args: args}
 	for filePath := range ch {
-		if filePath, err = tags.InlineExtract(filePath); err != nil {
+		if err := queueEntry(ctx, args, run, filePath, true); err != nil {
 			return err
 		}
-		en, err := entry.New(filePath)
-		if err != nil {
+	}
+
+	return nil
+}
+
+// Queue a single *.txt into ./db/*.txt.STAMP.queued. If not interactive, entries
+// which would require a prompt (e.g. without hashtags) are only reported and kept
+// in the inbox for the next interactive run.
+func queueEntry(ctx context.Context, args config.Args, run *checks, filePath string, interactive bool) error {
+	filePath, err := tags.InlineExtract(filePath)
+	if err != nil {
+		return err
+	}
+	en, err := entry.New(filePath)
+	if err != nil {
+		return err
+	}
+
+	hasDefault, err := en.HasDefaultContent()
+	if err != nil {
+		return err
+	}
+	if !hasDefault {
+		colour.Warnln("The entry", en.Path, "has got no default text, only platform variants")
+		colour.Infoln("Keeping", en.Path, "in the inbox")
+		return nil
+	}
+
+	hasHashtags, err := en.HasHashtags()
+	if err != nil {
+		return err
+	}
+	if !hasHashtags {
+		if interactive {
+			colour.Warnln("The following entry has got no hashtags:")
+		} else {
+			colour.Warnln("The entry", en.Path, "has got no hashtags")
+		}
+	}
+	if !hasHashtags || en.HasTag("ask") {
+		if !interactive {
+			colour.Infoln("Keeping", en.Path, "in the inbox until the next interactive run")
+			return nil
+		}
+		if content, _, err := en.Content(); err == nil {
+			if history, err := run.hashtags(); err != nil {
+				colour.Infoln("Unable to suggest hashtags:", err)
+			} else {
+				history.PrintSuggestions(content)
+			}
+		}
+		if err := fileAction(args, en, "Do you want to queue this"); err != nil {
 			return err
 		}
+	}
 
-		hasHashtags, err := en.HasHashtags()
-		if err != nil {
+	if ok, err := checkDuplicates(args, run, en, interactive); err != nil || !ok {
+		return err
+	}
+	fits, err := checkSizeLimits(args, en, interactive)
+	if err != nil {
+		return err
+	}
+	if !fits {
+		colour.Infoln("Keeping", en.Path, "in the inbox until the next interactive run")
+		return nil
+	}
+	if ok, err := checkDocument(args, en); err != nil || !ok {
+		if err == nil {
+			colour.Infoln("Keeping", en.Path, "in the inbox")
+		}
+		return err
+	}
+	if ok, err := lintEntry(ctx, args, run, en.Path); err != nil || !ok {
+		return err
+	}
+
+	destPath := fmt.Sprintf("%s/db/%s.%s.queued", args.GosDir, filepath.Base(en.Path), timestamp.Now())
+	if args.DryRun {
+		colour.Infoln("Not queueing entry", en.Path, "to", destPath, "as dry-run mode enabled")
+		return nil
+	}
+	if err := oi.Rename(en.Path, destPath); err != nil {
+		return err
+	}
+	return journal.Record(journal.Op{Kind: journal.Queue, From: en.Path, To: destPath})
+}
+
+// Checks whether the entry is a (near) duplicate of an entry queued or posted
+// within the duplicate window. If interactive, it asks whether to queue it anyway,
+// otherwise (or with -duplicateRefuse) the entry is kept in the inbox.
+func checkDuplicates(args config.Args, run *checks, en entry.Entry, interactive bool) (bool, error) {
+	index, err := run.duplicates()
+	if err != nil {
+		return false, err
+	}
+	matches, err := index.Find(en, args.DuplicateThreshold, args.DuplicateLinkThreshold)
+	if err != nil || len(matches) == 0 {
+		return err == nil, err
+	}
+	colour.Warnln("The entry", en.Path, "looks like a duplicate of:")
+	for _, m := range matches {
+		colour.Infoln(" ", m)
+	}
+	if args.DuplicateRefuse {
+		colour.Infoln("Keeping", en.Path, "in the inbox")
+		return false, nil
+	}
+	if !interactive {
+		colour.Warnln("Keeping", en.Path, "in the inbox as a possible duplicate until the next interactive run")
+		return false, nil
+	}
+	question := fmt.Sprintf("Possible duplicate of %s (%s %s), queue this anyway",
+		matches[0].Path, matches[0].State, matches[0].Time.Format(time.DateTime))
+	return true, fileAction(args, en, question)
+}
+
+// Runs the file action prompt of the entry. An entry deleted via the prompt is
+// moved into the trashbin.
+func fileAction(args config.Args, en entry.Entry, question string) error {
+	err := en.FileAction(question)
+	if errors.Is(err, prompt.ErrDeleted) {
+		if err := moveToTrash(args, en.Path); err != nil {
 			return err
 		}
-		if !hasHashtags {
-			colour.Warnln("The following entry has got no hashtags:")
+	}
+	return err
+}
+
+// Checks the content variant of every platform the entry will be queued to against
+// the size limit of the platform. If interactive, the entry can be edited until
+// it fits, otherwise it is only reported.
+func checkSizeLimits(args config.Args, en entry.Entry, interactive bool) (bool, error) {
+	share, err := tags.NewShare(args, en.Tags)
+	if err != nil {
+		return false, err
+	}
+	for platformStr, sizeLimit := range args.Platforms {
+		platform, err := platforms.New(platformStr)
+		if err != nil {
+			return false, err
 		}
-		if !hasHashtags || en.HasTag("ask") {
-			if err := en.FileAction("Do you want to queue this"); err != nil {
-				return err
+		if share.Excluded(platform.String()) {
+			continue
+		}
+		if interactive {
+			if _, _, err := en.ContentWithLimit(platform.String(), sizeLimit, args.Filters); err != nil {
+				return false, err
 			}
+			continue
+		}
+		content, _, err := en.ContentFor(platform.String(), args.Filters)
+		if err != nil {
+			return false, err
+		}
+		if length := textlen.Count(platform.String(), content); length > sizeLimit {
+			colour.Warnln(fmt.Sprintf("The %s content of %s exceeds the size limit (%d > %d)",
+				platform, en.Path, length, sizeLimit))
+			return false, nil
 		}
+	}
+	return true, nil
+}
 
-		destPath := fmt.Sprintf("%s/db/%s.%s.queued", args.GosDir, filepath.Base(en.Path), timestamp.Now())
-		if args.DryRun {
-			colour.Infoln("Not queueing entry", en.Path, "to", destPath, "as dry-run mode enabled")
-			continue
+// Checks the document (PDF) of the entry against the size and page limits of
+// LinkedIn, so that an oversized slide deck isn't only found when posting it. The
+// document is only checked if the entry is shared to a LinkedIn platform.
+func checkDocument(args config.Args, en entry.Entry) (bool, error) {
+	doc := en.FrontMatter.Document
+	if doc.Path == "" {
+		return true, nil
+	}
+	linkedIn, err := sharedToLinkedIn(args, en)
+	if err != nil || !linkedIn {
+		return true, err
+	}
+	if _, _, err := document.Check(document.Resolve(args.GosDir, doc.Path)); err != nil {
+		colour.Warnln("The document of", en.Path, "can't be posted:", err)
+		return false, nil
+	}
+	return true, nil
+}
+
+// Returns whether a LinkedIn platform (profile or organisation page) is enabled
+// and not excluded by the share tags of the entry.
+func sharedToLinkedIn(args config.Args, en entry.Entry) (bool, error) {
+	share, err := tags.NewShare(args, en.Tags)
+	if err != nil {
+		return false, err
+	}
+	for platformStr := range args.Platforms {
+		platform, err := platforms.New(platformStr)
+		if err != nil {
+			return false, err
 		}
-		if err := oi.Rename(en.Path, destPath); err != nil {
-			return err
+		if config.Network(platform.String()) == "linkedin" && !share.Excluded(platform.String()) {
+			return true, nil
 		}
 	}
+	return false, nil
+}
 
-	return nil
+// Lints the entry and prints the report, if there are any issues. With -lintBlock,
+// entries with lint errors are kept in the inbox.
+func lintEntry(ctx context.Context, args config.Args, run *checks, filePath string) (bool, error) {
+	linter, err := run.lint()
+	if err != nil {
+		return false, err
+	}
+	report := linter.Check(ctx, filePath)
+	if len(report) == 0 {
+		return true, nil
+	}
+	if err := report.Render(); err != nil {
+		return false, err
+	}
+	if args.LintBlock && report.HasErrors() {
+		colour.Warnln("Keeping", filePath, "in the inbox due to lint errors")
+		return false, nil
+	}
+	return true, nil
+}
+
+// checks are shared by all entries of a queue run, so that the other entries are
+// read and the links are checked only once. They are created when first needed.
+type checks struct {
+	args    config.Args
+	linter  *lint.Linter
+	index   *dedup.Index
+	history *hashtag.History
+}
+
+func (c *checks) lint() (*lint.Linter, error) {
+	if c.linter == nil {
+		linter, err := lint.New(c.args)
+		if err != nil {
+			return nil, err
+		}
+		c.linter = linter
+	}
+	return c.linter, nil
+}
+
+func (c *checks) duplicates() (*dedup.Index, error) {
+	if c.index == nil {
+		index, err := dedup.NewIndex(c.args.GosDir, c.args.DuplicateWindow)
+		if err != nil {
+			return nil, err
+		}
+		c.index = index
+	}
+	return c.index, nil
+}
+
+func (c *checks) hashtags() (hashtag.History, error) {
+	if c.history == nil {
+		history, err := hashtag.LoadHistory(c.args.GosDir)
+		if err != nil {
+			return history, err
+		}
+		c.history = &history
+	}
+	return *c.history, nil
 }
 
 // Queue all ./db/queued/*.txt.STAMP.queued into ./db/platforms/PLATFORM/*.txt.STAMP.queued
@@ -80,7 +325,7 @@ func queueEntriesToPlatforms(args config.Args) error {
 		return err
 	}
 
-	trashDir := filepath.Join(args.GosDir, "db", "trashbin")
+	trashbin := trash.New(args)
 	for filePath := range ch {
 		en, err := entry.New(filePath)
 		if err != nil {
@@ -109,18 +354,12 @@ func queueEntriesToPlatforms(args config.Args) error {
 		}
 
 		// Keep queued items in trash for a while.
-		trashPath := filepath.Join(trashDir, strings.TrimSuffix(filepath.Base(en.Path), ".queued")+".trash")
-		colour.Infofln("Trashing %s -> %s", en.Path, trashPath)
-		if err := oi.EnsureParentDir(trashPath); err != nil {
-			return err
-		}
-		if err := os.Rename(en.Path, trashPath); err != nil {
+		if _, err := trashbin.Move(en.Path); err != nil {
 			return err
 		}
 	}
 
-	sixMonthsAgo := time.Now().AddDate(0, -6, 0)
-	return deleteFiles(trashDir, ".trash", sixMonthsAgo)
+	return trashbin.Purge()
 }
 
 // Queue ./db/queued/*.txt.STAMP.queued to ./db/platforms/PLATFORM/*.txt.STAMP.queued
@@ -136,28 +375,10 @@ func queuePlatform(en entry.Entry, gosDir string, platform platforms.Platform) e
 	}
 
 	colour.Infoln("Queuing", en.Path, "->", destPath)
-	return oi.CopyFile(en.Path, destPath)
-}
-
-func deleteFiles(path, suffix string, olderThan time.Time) error {
-	ch, err := oi.ReadDirCh(path, find(path, suffix))
-	if err != nil {
+	if err := oi.CopyFile(en.Path, destPath); err != nil {
 		return err
 	}
-	for filePath := range ch {
-		fileInfo, err := os.Stat(filePath)
-		if err != nil {
-			return err
-		}
-		if fileInfo.ModTime().Before(olderThan) {
-			colour.Infoln("Cleaning up", filePath)
-			err := os.Remove(filePath)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+	return journal.Record(journal.Op{Kind: journal.QueuePlatform, From: en.Path, To: destPath})
 }
 
 func find(path string, suffixes ...string) func(os.DirEntry) (string, bool) {
@@ -0,0 +1,83 @@
+package queue
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueueEntrySizeLimitVariants(t *testing.T) {
+	args := newTestGosDir(t)
+	args.Platforms = map[string]int{"Mastodon": 20, "LinkedIn": 1000}
+	files := map[string]string{
+		"fits.txt":      "A long text for LinkedIn #foo\n--- mastodon ---\nShort #foo",
+		"toolong.txt":   "Short #foo\n--- mastodon ---\nA too long text for Mastodon #foo",
+		"nodefault.txt": "--- mastodon ---\nShort #foo",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(args.GosDir, name)
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := queueEntry(context.Background(), args, &checks{args: args}, filePath, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectFiles(t, args.GosDir, "nodefault.txt", "toolong.txt")
+	queued, err := filepath.Glob(filepath.Join(args.GosDir, "db/fits.txt.*.queued"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 1 {
+		t.Errorf("expected fits.txt to be queued but got %v", queued)
+	}
+}
+
+func TestQueueEntryLintBlock(t *testing.T) {
+	args := newTestGosDir(t)
+	args.Platforms = map[string]int{"Mastodon": 500}
+	args.LintBlock = true
+	filePath := filepath.Join(args.GosDir, "image.md")
+	if err := os.WriteFile(filePath, []byte("A missing image #foo\n\n![cat](./cat.jpg)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := queueEntry(context.Background(), args, &checks{args: args}, filePath, false); err != nil {
+		t.Fatal(err)
+	}
+	expectFiles(t, args.GosDir, "image.md")
+}
+
+func TestQueueEntryDocument(t *testing.T) {
+	args := newTestGosDir(t)
+	args.Platforms = map[string]int{"Mastodon": 500, "LinkedIn": 1000}
+	files := map[string]string{
+		"slides.pdf":  "%PDF-1.4\n<< /Type /Pages /Count 12 >>\n%%EOF\n",
+		"huge.pdf":    "%PDF-1.4\n<< /Type /Pages /Count 1000 >>\n%%EOF\n",
+		"slides.txt":  "---\ndocument:\n  path: ./slides.pdf\n---\nMy slides #foo",
+		"huge.txt":    "---\ndocument:\n  path: ./huge.pdf\n---\nMy huge slides #foo",
+		"missing.txt": "---\ndocument:\n  path: ./missing.pdf\n---\nMy missing slides #foo",
+		// Not shared to LinkedIn, so the document isn't checked.
+		"mastodon.share:-linkedin.txt": "---\ndocument:\n  path: ./huge.pdf\n---\nMy huge slides #foo",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(args.GosDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"slides.txt", "huge.txt", "missing.txt", "mastodon.share:-linkedin.txt"} {
+		if err := queueEntry(context.Background(), args, &checks{args: args}, filepath.Join(args.GosDir, name), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectFiles(t, args.GosDir, "huge.pdf", "huge.txt", "missing.txt", "slides.pdf")
+	for _, name := range []string{"slides.txt", "mastodon.share:-linkedin.txt"} {
+		queued, err := filepath.Glob(filepath.Join(args.GosDir, "db", name+".*.queued"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(queued) != 1 {
+			t.Errorf("expected %s to be queued but got %v", name, queued)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package queue
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/oi"
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// How long a file must not change until it is queued.
+	watchSettleTime = 2 * time.Second
+	watchTick       = 500 * time.Millisecond
+)
+
+// Suffixes of editor swap, backup and temp files, including the .tmp files written
+// by oi.WriteFile.
+var ignoredWatchSuffixes = []string{".tmp", ".swp", ".swo", ".swx", "~", ".bak", ".part"}
+
+// Watch watches the gosDir (the inbox) for new or changed entries and queues them
+// as soon as they stop changing, until the context is cancelled. Entries which
+// would require a prompt (e.g. without hashtags) are reported and kept in the inbox.
+func Watch(ctx context.Context, args config.Args) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := watcher.Close(); err != nil {
+			colour.Errorln("Error closing watcher:", err)
+		}
+	}()
+	if err := oi.EnsureDir(args.GosDir); err != nil {
+		return err
+	}
+	if err := watcher.Add(args.GosDir); err != nil {
+		return err
+	}
+
+	// Process everything which is already in the inbox first.
+	paths, err := oi.ReadDir(args.GosDir, find(args.GosDir, validExtensions...))
+	if err != nil {
+		return err
+	}
+	run := &checks{args: args}
+	for _, filePath := range paths {
+		if !ignoredInWatch(filePath) {
+			watchQueue(ctx, args, run, filePath)
+		}
+	}
+
+	colour.Infoln("Watching", args.GosDir, "for new entries, press Ctrl+C to stop")
+	ticker := time.NewTicker(watchTick)
+	defer ticker.Stop()
+	pending := make(map[string]time.Time)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			colour.Errorln("Error watching", args.GosDir, ":", err)
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if ignoredInWatch(event.Name) {
+				continue
+			}
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
+				pending[event.Name] = time.Now()
+			}
+		case now := <-ticker.C:
+			// New checks for every batch, as the entries change while watching.
+			run := &checks{args: args}
+			for filePath, lastChange := range pending {
+				if now.Sub(lastChange) < watchSettleTime {
+					continue
+				}
+				delete(pending, filePath)
+				// E.g. already renamed by inline tag extraction or queued.
+				if !oi.IsRegular(filePath) {
+					continue
+				}
+				watchQueue(ctx, args, run, filePath)
+			}
+		}
+	}
+}
+
+// watchQueue queues the entry without blocking on any prompt. Errors are only
+// reported, so that watching continues.
+func watchQueue(ctx context.Context, args config.Args, run *checks, filePath string) {
+	colour.Infoln("Processing", filePath)
+	if err := queueEntry(ctx, args, run, filePath, false); err != nil {
+		colour.Errorln("Unable to queue", filePath, ":", err)
+		return
+	}
+	if err := queueEntriesToPlatforms(args); err != nil {
+		colour.Errorln("Unable to queue to platforms:", err)
+	}
+}
+
+func ignoredInWatch(filePath string) bool {
+	name := filepath.Base(filePath)
+	// Hidden files, e.g. vim's .foo.txt.swp or emacs' .#foo.txt lock files.
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "#") {
+		return true
+	}
+	for _, suffix := range ignoredWatchSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	if !slices.Contains(validExtensions, filepath.Ext(name)) {
+		return true
+	}
+	info, err := os.Stat(filePath)
+	return err == nil && info.IsDir()
+}
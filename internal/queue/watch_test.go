@@ -0,0 +1,63 @@
+package queue
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIgnoredInWatch(t *testing.T) {
+	table := map[string]bool{
+		"/gosdir/foo.txt":          false,
+		"/gosdir/foo.md":           false,
+		"/gosdir/foo.txt.tmp":      true,
+		"/gosdir/.foo.txt.swp":     true,
+		"/gosdir/foo.txt~":         true,
+		"/gosdir/.#foo.txt":        true,
+		"/gosdir/#foo.txt#":        true,
+		"/gosdir/4913":             true,
+		"/gosdir/foo.share:ma.txt": false,
+	}
+	for filePath, expected := range table {
+		if ignored := ignoredInWatch(filePath); ignored != expected {
+			t.Errorf("expected ignored=%v for %s but got %v", expected, filePath, ignored)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	args := newTestGosDir(t)
+	args.Platforms = map[string]int{"Mastodon": 500}
+
+	ctx, cancel := context.WithTimeout(context.Background(), watchSettleTime+3*time.Second)
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- Watch(ctx, args) }()
+
+	// Give the watcher some time to start up.
+	time.Sleep(500 * time.Millisecond)
+	files := map[string]string{
+		"foo.txt":      "Hello world #foo",
+		"nohashes.txt": "Hello world",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(args.GosDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	queued, err := filepath.Glob(filepath.Join(args.GosDir, "db/platforms/mastodon/foo.txt.*.queued"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 1 {
+		t.Errorf("expected foo.txt to be queued to mastodon but got %v", queued)
+	}
+	// Entries without hashtags require a prompt, so they stay in the inbox.
+	expectFiles(t, args.GosDir, "nohashes.txt")
+}
@@ -4,15 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"codeberg.org/snonux/gos/internal/colour"
 	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/entry"
+	"codeberg.org/snonux/gos/internal/hashtag"
+	"codeberg.org/snonux/gos/internal/oi"
 	"codeberg.org/snonux/gos/internal/platforms"
 	"codeberg.org/snonux/gos/internal/prompt"
 	"codeberg.org/snonux/gos/internal/queue"
 	"codeberg.org/snonux/gos/internal/schedule"
 	"codeberg.org/snonux/gos/internal/summary"
+	"codeberg.org/snonux/gos/internal/table"
+	"codeberg.org/snonux/gos/internal/textlen"
+	"codeberg.org/snonux/gos/internal/trash"
 )
 
 func run(ctx context.Context, args config.Args) error {
@@ -35,7 +42,7 @@ func run(ctx context.Context, args config.Args) error {
 	}
 
 	// Run queue operations
-	if err := runQueueOperations(args); err != nil {
+	if err := runQueueOperations(ctx, args); err != nil {
 		return err
 	}
 
@@ -44,6 +51,14 @@ func run(ctx context.Context, args config.Args) error {
 		return err
 	}
 
+	// Only posting needs the secrets, so that composing doesn't ask for the
+	// passphrase of the encrypted credential store.
+	if !args.ComposeMode && needsSecrets(args) {
+		if err := args.Config.UnlockStore(args.ConfigPath); err != nil {
+			return err
+		}
+	}
+
 	// Post to platforms
 	if err := postToPlatforms(ctx, args); err != nil {
 		return err
@@ -73,12 +88,27 @@ func runPlatform(ctx context.Context, args config.Args, platform platforms.Platf
 	}
 
 	err = platform.Post(ctx, args, sizeLimit, en)
+	if errors.Is(err, prompt.ErrDeleted) {
+		// Entries deleted via the prompt go into the trashbin.
+		if err := trashEntry(args, en.Path); err != nil {
+			return err
+		}
+	}
 	if errors.Is(err, prompt.ErrRamdomOther) || errors.Is(err, prompt.ErrDeleted) {
 		return runPlatform(ctx, args, platform, sizeLimit)
 	}
 	return err
 }
 
+func trashEntry(args config.Args, filePath string) error {
+	if args.DryRun {
+		colour.Infoln("Not trashing", filePath, "as dry-run mode enabled")
+		return nil
+	}
+	_, err := trash.New(args).Move(filePath)
+	return err
+}
+
 func checkPauseStatus(args config.Args) error {
 	// Check if posting is paused
 	paused, err := args.Config.IsPaused()
@@ -97,11 +127,62 @@ func handleComposeMode(args config.Args) error {
 	if err := prompt.EditFile(entryPath); err != nil {
 		return err
 	}
+	return printContentLengths(args, entryPath)
+}
+
+// Prints the length of the composed entry as counted by each platform, so that it
+// can be shortened right away and not only once it is posted.
+func printContentLengths(args config.Args, entryPath string) error {
+	if !oi.IsRegular(entryPath) {
+		return nil
+	}
+	en, err := entry.New(entryPath)
+	if err != nil {
+		return err
+	}
+
+	platformStrs := make([]string, 0, len(args.Platforms))
+	for platformStr := range args.Platforms {
+		platformStrs = append(platformStrs, platformStr)
+	}
+	slices.Sort(platformStrs)
+
+	tab := table.New().Header("Platform", "Length", "Limit", "Status")
+	for _, platformStr := range platformStrs {
+		platform, err := platforms.New(platformStr)
+		if err != nil {
+			return err
+		}
+		content, _, err := en.ContentFor(platform.String(), args.Filters)
+		if err != nil {
+			return err
+		}
+		length, sizeLimit := textlen.Count(platform.String(), content), args.Platforms[platformStr]
+		status := "ok"
+		if length > sizeLimit {
+			status = fmt.Sprintf("%d too long", length-sizeLimit)
+		}
+		tab.Row(platformStr, length, sizeLimit, status)
+	}
+	if err := tab.Render(); err != nil {
+		return err
+	}
+
+	content, _, err := en.Content()
+	if err != nil {
+		return err
+	}
+	history, err := hashtag.LoadHistory(args.GosDir)
+	if err != nil {
+		colour.Infoln("Unable to suggest hashtags:", err)
+		return nil
+	}
+	history.PrintSuggestions(content)
 	return nil
 }
 
-func runQueueOperations(args config.Args) error {
-	if err := queue.Run(args); err != nil {
+func runQueueOperations(ctx context.Context, args config.Args) error {
+	if err := queue.Run(ctx, args); err != nil {
 		if !softError(err) {
 			return err
 		}
@@ -120,6 +201,16 @@ func checkRunInterval(args config.Args) error {
 	return nil
 }
 
+// Reports whether any platform enabled posts with credentials, i.e. isn't Noop.
+func needsSecrets(args config.Args) bool {
+	for platformStr := range args.Platforms {
+		if config.Network(platformStr) != "noop" {
+			return true
+		}
+	}
+	return false
+}
+
 func postToPlatforms(ctx context.Context, args config.Args) error {
 	for platformStr, sizeLimit := range args.Platforms {
 		platform, err := platforms.New(platformStr)
@@ -3,10 +3,11 @@ package schedule
 import (
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
-	"slices"
 	"strings"
+	"time"
 
 	"codeberg.org/snonux/gos/internal/colour"
 	"codeberg.org/snonux/gos/internal/config"
@@ -14,6 +15,9 @@ import (
 	"codeberg.org/snonux/gos/internal/oi"
 	"codeberg.org/snonux/gos/internal/platforms"
 	"codeberg.org/snonux/gos/internal/prompt"
+	"codeberg.org/snonux/gos/internal/series"
+	"codeberg.org/snonux/gos/internal/timestamp"
+	"golang.org/x/exp/rand"
 )
 
 var (
@@ -36,7 +40,10 @@ func Run(args config.Args, platform platforms.Platform) (entry.Entry, error) {
 		)
 	}
 
-	en, err := selectEntry(dir)
+	// Recycle evergreen entries only when the fresh queue is low, and only up to
+	// their share of the weekly target.
+	recycle := stats.queued < args.MinQueued && stats.recycled < recycleBudget(args)
+	en, err := selectEntry(dir, platform.String(), recycle, args)
 	if err != nil && !errors.Is(err, oi.ErrNotFound) {
 		return en, err
 	}
@@ -52,11 +59,22 @@ func Run(args config.Args, platform platforms.Platform) (entry.Entry, error) {
  * 2. Any entry with the prio tag
  * 3. Any entry with the soon tag
  * 4. Any other entry
+ * 5. Any posted evergreen entry to recycle, if recycling
  */
-func selectEntry(dir string) (en entry.Entry, err error) {
+func selectEntry(dir, platform string, recycle bool, args config.Args) (en entry.Entry, err error) {
+	// Series are posted in order.
+	seriesIndex, err := series.NewIndex(dir)
+	if err != nil {
+		return
+	}
+	// Parsed once for all passes below.
+	queued, err := readEntries(dir, platform, entry.Queued)
+	if err != nil {
+		return
+	}
 	tagsToTry := []string{"now", "prio", "soon", ""}
 	for _, tag := range tagsToTry {
-		if en, err = selectRandomEntry(dir, tag); err == nil {
+		if en, err = selectRandomEntry(queued, seriesIndex, tag); err == nil {
 			return
 		}
 		if !errors.Is(err, oi.ErrNotFound) {
@@ -64,16 +82,65 @@ func selectEntry(dir string) (en entry.Entry, err error) {
 		}
 
 	}
+	if recycle {
+		var posted []entry.Entry
+		if posted, err = readEntries(dir, platform, entry.Posted); err != nil {
+			return
+		}
+		if en, err = selectRecycledEntry(posted, dir, seriesIndex, args); err == nil {
+			return
+		}
+		if !errors.Is(err, oi.ErrNotFound) {
+			return
+		}
+	}
 	err = ErrNothingQueued
 	return
 }
 
 // Select a random queed entry with a given tag. If the tag is the empty string,
-// then select any random qeued entry.
-func selectRandomEntry(dir, tag string) (entry.Entry, error) {
-	return oi.ReadDirRandom(dir, func(file os.DirEntry) (entry.Entry, bool) {
-		// Is there a ".TAG." in the file name?
-		if tag != "" && !slices.Contains(strings.Split(file.Name(), "."), tag) {
+// then select any random qeued entry. The tags can be in the file name or in the
+// front matter.
+func selectRandomEntry(queued []entry.Entry, seriesIndex series.Index, tag string) (entry.Entry, error) {
+	return selectRandom(queued, seriesIndex, func(en entry.Entry) bool {
+		return tag == "" || en.HasTag(tag)
+	})
+}
+
+// Select a random posted evergreen entry, which was last posted at least the
+// minimum age ago and wasn't reposted too often yet.
+func selectRecycledEntry(posted []entry.Entry, dir string, seriesIndex series.Index, args config.Args) (entry.Entry, error) {
+	reposts, err := entry.Reposts(dir)
+	if err != nil {
+		return entry.Zero, err
+	}
+	lastPostedBefore := timestamp.NowTime().Add(-args.EvergreenMinAge)
+	return selectRandom(posted, seriesIndex, func(en entry.Entry) bool {
+		if !en.HasTag("evergreen") {
+			return false
+		}
+		repostTimes := reposts[en.UntaggedName()]
+		lastPosted := en.Time
+		for _, repostTime := range repostTimes {
+			if repostTime.After(lastPosted) {
+				lastPosted = repostTime
+			}
+		}
+		return lastPosted.Before(lastPostedBefore) && len(repostTimes) < args.EvergreenMaxReposts
+	})
+}
+
+// The number of recycled posts per week allowed.
+func recycleBudget(args config.Args) int {
+	return int(math.Ceil(float64(args.Target) * args.EvergreenShare))
+}
+
+// Reads the entries of the dir in the given state. The state is checked by the
+// file name suffix first, so that only the candidates are parsed.
+func readEntries(dir, platform string, state entry.State) ([]entry.Entry, error) {
+	suffix := "." + state.String()
+	return oi.ReadDir(dir, func(file os.DirEntry) (entry.Entry, bool) {
+		if !file.Type().IsRegular() || !strings.HasSuffix(file.Name(), suffix) {
 			return entry.Zero, false
 		}
 		en, err := entry.New(filepath.Join(dir, file.Name()))
@@ -81,6 +148,32 @@ func selectRandomEntry(dir, tag string) (entry.Entry, error) {
 			colour.Infoln(err)
 			return entry.Zero, false
 		}
-		return en, en.State == entry.Queued
+		return en.ForPlatform(platform), en.State == state
 	})
 }
+
+// Select a random wanted entry. Entries which aren't due yet or expired, and series
+// parts whose previous parts weren't posted yet, are skipped.
+func selectRandom(entries []entry.Entry, seriesIndex series.Index, wanted func(entry.Entry) bool) (entry.Entry, error) {
+	now := time.Now()
+	var candidates []entry.Entry
+	for _, en := range entries {
+		if !wanted(en) {
+			continue
+		}
+		if ok, reason := en.Eligible(now); !ok {
+			colour.Infoln("Skipping", en.Path, "as it is", reason)
+			continue
+		}
+		if !seriesIndex.Eligible(en) {
+			colour.Infoln("Skipping", en.Path, "as the previous part of its series isn't posted yet")
+			continue
+		}
+		candidates = append(candidates, en)
+	}
+	if len(candidates) == 0 {
+		return entry.Zero, oi.ErrNotFound
+	}
+	rand.Seed(uint64(time.Now().UnixNano()))
+	return candidates[rand.Intn(len(candidates))], nil
+}
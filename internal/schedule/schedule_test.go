@@ -0,0 +1,86 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/timestamp"
+)
+
+func TestSelectEntryEvergreen(t *testing.T) {
+	gosDir := t.TempDir()
+	dir := filepath.Join(gosDir, "db", "platforms", "mastodon")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().AddDate(-2, 0, 0).Format(timestamp.Format)
+	recent := time.Now().AddDate(0, -1, 0).Format(timestamp.Format)
+	files := []string{
+		"fresh.txt." + recent + ".queued",
+		"recent.evergreen.txt." + recent + ".posted",
+		"tooOften.evergreen.txt." + old + ".posted",
+		"notEvergreen.txt." + old + ".posted",
+		"recentlyReposted.evergreen.txt." + old + ".posted",
+		"old.evergreen.txt." + old + ".posted",
+	}
+	for _, name := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("Hello #foo"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reposts := fmt.Sprintf(`{"mastodon": {%q: [%q, %q, %q], %q: [%q], %q: [%q]}}`,
+		"tooOften.txt", old, old, old, "recentlyReposted.txt", recent, "old.txt", old)
+	if err := os.WriteFile(filepath.Join(gosDir, "db", "reposts.json"), []byte(reposts), 0644); err != nil {
+		t.Fatal(err)
+	}
+	args := config.Args{EvergreenMinAge: 365 * 24 * time.Hour, EvergreenMaxReposts: 3}
+
+	// Fresh entries are selected first, even when recycling.
+	for _, recycle := range []bool{false, true} {
+		en, err := selectEntry(dir, "mastodon", recycle, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := filepath.Join(dir, files[0]); en.Path != expected {
+			t.Errorf("expected %s but got %s", expected, en.Path)
+		}
+	}
+
+	// Without fresh entries, the only eligible evergreen entry is recycled.
+	if err := os.Remove(filepath.Join(dir, files[0])); err != nil {
+		t.Fatal(err)
+	}
+	en, err := selectEntry(dir, "mastodon", true, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(dir, files[5]); en.Path != expected {
+		t.Errorf("expected %s but got %s", expected, en.Path)
+	}
+	if _, err := selectEntry(dir, "mastodon", false, args); !errors.Is(err, ErrNothingQueued) {
+		t.Errorf("expected %v without recycling but got %v", ErrNothingQueued, err)
+	}
+}
+
+func TestRecycleBudget(t *testing.T) {
+	table := []struct {
+		target   int
+		share    float64
+		expected int
+	}{
+		{2, 0.25, 1},
+		{8, 0.25, 2},
+		{2, 0, 0},
+	}
+	for _, tt := range table {
+		args := config.Args{Target: tt.target, EvergreenShare: tt.share}
+		if got := recycleBudget(args); got != tt.expected {
+			t.Errorf("expected budget %d for target %d and share %v but got %d", tt.expected, tt.target, tt.share, got)
+		}
+	}
+}
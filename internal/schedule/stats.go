@@ -11,6 +11,7 @@ import (
 	"codeberg.org/snonux/gos/internal/entry"
 	"codeberg.org/snonux/gos/internal/oi"
 	"codeberg.org/snonux/gos/internal/platforms"
+	"codeberg.org/snonux/gos/internal/series"
 	"codeberg.org/snonux/gos/internal/table"
 	"codeberg.org/snonux/gos/internal/timestamp"
 )
@@ -24,6 +25,8 @@ type stats struct {
 	postsPerDay       float64
 	postsPerDayTarget float64
 	lastPostDaysAgo   float64
+	recycled          int // Recycled evergreen posts within the last week
+	series            []series.Progress
 
 	totalPosted      int
 	totalSinceDays   float64
@@ -42,6 +45,10 @@ func newStats(gosDir string, platformName string, lookback time.Duration, target
 	if err := s.gatherQueuedStats(dir); err != nil {
 		return s, err
 	}
+	var err error
+	if s.series, err = series.ProgressOf(dir); err != nil {
+		return s, err
+	}
 
 	// Dynamically increase the target when there are many entries queued.
 	if s.queuedForDays > float64(maxQueuedDays) {
@@ -80,8 +87,12 @@ func (s *stats) gatherPostedStats(dir string, lookbackTime time.Time, cfg config
 		oldest      time.Time = now // Oldest since lookbackTime
 		totalOldest time.Time = now // All time oldest
 	)
+	reposts, err := entry.Reposts(dir)
+	if err != nil {
+		return err
+	}
 
-	err := oi.ForeachDirEntry(dir, func(file os.DirEntry) error {
+	err = oi.ForeachDirEntry(dir, func(file os.DirEntry) error {
 		filePath := filepath.Join(dir, file.Name())
 		ent, err := entry.New(filePath)
 		if err != nil {
@@ -90,24 +101,33 @@ func (s *stats) gatherPostedStats(dir string, lookbackTime time.Time, cfg config
 		if ent.State != entry.Posted {
 			return nil
 		}
-		if ent.Time.Before(totalOldest) {
-			totalOldest = ent.Time
-		}
-		s.totalPosted++
-		if ent.Time.Before(lookbackTime) {
-			return nil
-		}
-		// Ignore .now.
-		if strings.Contains(file.Name(), ".now.") {
-			return nil
-		}
-		if ent.Time.Before(oldest) {
-			oldest = ent.Time
+		// Every repost of a recycled evergreen entry counts as a post of its own.
+		repostTimes := reposts[ent.UntaggedName()]
+		for _, postTime := range append([]time.Time{ent.Time}, repostTimes...) {
+			if postTime.Before(totalOldest) {
+				totalOldest = postTime
+			}
+			s.totalPosted++
+			if postTime.Before(lookbackTime) {
+				continue
+			}
+			// Ignore now tagged entries, as they don't count towards the target.
+			if ent.ForPlatform(filepath.Base(dir)).HasTag("now") {
+				continue
+			}
+			if postTime.Before(oldest) {
+				oldest = postTime
+			}
+			if postTime.After(newest) {
+				newest = postTime
+			}
+			s.posted++
 		}
-		if ent.Time.After(newest) {
-			newest = ent.Time
+		for _, repostTime := range repostTimes {
+			if repostTime.After(now.Add(-7 * 24 * time.Hour)) {
+				s.recycled++
+			}
 		}
-		s.posted++
 		return nil
 	})
 	if err != nil {
@@ -164,17 +184,20 @@ func (s *stats) gatherQueuedStats(dir string) error {
 }
 
 func (s stats) RenderTable(platform platforms.Platform) {
-	table.New().
+	tab := table.New().
 		WithColor(colour.AttentionCol).
 		Header(platform.String(), "value", "Lifetime stats", "value").
 		Row("Since (days)", s.sinceDays, "Total since (days)", s.totalSinceDays).
 		Row("#Posted entries", s.posted, "#Total posted entries", s.totalPosted).
-		Row("#Queued entries", s.queued, "", "").
+		Row("#Queued entries", s.queued, "#Recycled last week", s.recycled).
 		Row("Enough for (days)", s.queuedForDays, "", "").
 		Row("Last post (days ago)", s.lastPostDaysAgo, "Pause days", s.pauseDays).
 		Row("Posts per day", s.postsPerDay, "Total posts per day", s.totalPostsPerDay).
-		Row("Posts per day target", s.postsPerDayTarget, "", "").
-		MustRender()
+		Row("Posts per day target", s.postsPerDayTarget, "", "")
+	for _, p := range s.series {
+		tab.Row("Series "+p.Name+" (posted)", p.String(), "", "")
+	}
+	tab.MustRender()
 }
 
 func pastTime(duration time.Duration) time.Time {
@@ -0,0 +1,207 @@
+// Package secrets manages the encrypted credential store: it sets, gets and lists
+// the secrets, and rotates the passphrase.
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/prompt"
+	"codeberg.org/snonux/gos/internal/table"
+)
+
+// PassphraseEnv is the environment variable with the passphrase of the store,
+// e.g. for unattended runs.
+const PassphraseEnv = "GOS_SECRETS_PASSPHRASE"
+
+// Passphrase returns the function which unlocks the store (see config.Passphrase).
+// It reads the key file, if any, otherwise the environment variable, otherwise it
+// prompts for the passphrase.
+func Passphrase(keyFile string) func(confirm bool) ([]byte, error) {
+	return func(confirm bool) ([]byte, error) {
+		if keyFile != "" {
+			return readKeyFile(keyFile)
+		}
+		if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+			return []byte(passphrase), nil
+		}
+		return askPassphrase(confirm)
+	}
+}
+
+// Set sets a secret in the store, creating the store first if there is none. The
+// setArgs are the name and the value, which is asked for if not given, so that it
+// doesn't end up in the shell history. An empty value deletes the secret.
+func Set(args config.Args, setArgs []string) error {
+	if len(setArgs) == 0 {
+		return errors.New("no secret name given")
+	}
+	name := setArgs[0]
+	if !slices.Contains(config.StoreSecrets, name) {
+		return unknownSecret(name)
+	}
+	var value string
+	if len(setArgs) > 1 {
+		value = setArgs[1]
+	} else {
+		input, err := prompt.Password(fmt.Sprintf("Value of %s:", name))
+		if err != nil {
+			return err
+		}
+		value = string(input)
+	}
+	if err := validate(name, value); err != nil {
+		return err
+	}
+	conf := args.Config
+	if err := conf.UnlockStore(args.ConfigPath); err != nil {
+		return err
+	}
+	if conf.Store() == nil {
+		colour.Infoln("Creating the encrypted credential store", config.StorePath(args.ConfigPath))
+		if err := conf.EnableStore(args.ConfigPath); err != nil {
+			return err
+		}
+	}
+	conf.Store().Set(name, value)
+	if err := conf.Store().Save(); err != nil {
+		return err
+	}
+	colour.Successfln("Set %s", name)
+	return nil
+}
+
+// Get prints a secret of the store, e.g. to pipe it into another program.
+func Get(args config.Args, name string) error {
+	store, err := openStore(args)
+	if err != nil {
+		return err
+	}
+	value, ok := store.Get(name)
+	if !ok {
+		return fmt.Errorf("no secret %s in %s", name, config.StorePath(args.ConfigPath))
+	}
+	fmt.Println(value)
+	return nil
+}
+
+// List prints the names of the secrets in the store, but not their values.
+func List(args config.Args) error {
+	store, err := openStore(args)
+	if err != nil {
+		return err
+	}
+	if len(store.Names()) == 0 {
+		colour.Infoln("No secrets in", config.StorePath(args.ConfigPath))
+		return nil
+	}
+	tab := table.New().Header("Secret")
+	for _, name := range store.Names() {
+		tab.Row(name)
+	}
+	return tab.Render()
+}
+
+// Rotate re-encrypts the store with a new passphrase, or with the key of a new key
+// file given by -keyFile.
+func Rotate(args config.Args, rotateArgs []string) error {
+	flags := flag.NewFlagSet("secrets rotate", flag.ContinueOnError)
+	keyFile := flags.String("keyFile", "", "The new key file, instead of a new passphrase")
+	if err := flags.Parse(rotateArgs); err != nil {
+		return err
+	}
+	store, err := openStore(args)
+	if err != nil {
+		return err
+	}
+
+	var passphrase []byte
+	if *keyFile != "" {
+		passphrase, err = readKeyFile(*keyFile)
+	} else {
+		colour.Infoln("Enter the new passphrase")
+		passphrase, err = askPassphrase(true)
+	}
+	if err != nil {
+		return err
+	}
+	if err := store.Rekey(passphrase); err != nil {
+		return err
+	}
+	if err := store.Save(); err != nil {
+		return err
+	}
+	colour.Successfln("Rotated the key of %s", config.StorePath(args.ConfigPath))
+	return nil
+}
+
+func openStore(args config.Args) (*config.Store, error) {
+	store := args.Config.Store()
+	if store == nil {
+		return nil, fmt.Errorf("no encrypted credential store %s, create it with 'gos secrets set'", config.StorePath(args.ConfigPath))
+	}
+	return store, nil
+}
+
+// Validates the value of the secret, the token expiries are Unix epochs.
+func validate(name, value string) error {
+	switch name {
+	case "LinkedInTokenExpiry", "LinkedInRefreshTokenExpiry":
+		if value == "" {
+			return nil
+		}
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("invalid %s, expected a Unix epoch: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func unknownSecret(name string) error {
+	return fmt.Errorf("unknown secret %s, expected one of %s", name, strings.Join(config.StoreSecrets, ", "))
+}
+
+// Reads the key file, which must be readable by the owner only, like the config.
+func readKeyFile(path string) ([]byte, error) {
+	if err := config.CheckPermissions(path); err != nil {
+		return nil, err
+	}
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key = bytes.TrimSpace(key)
+	if len(key) == 0 {
+		return nil, fmt.Errorf("empty key file %s", path)
+	}
+	return key, nil
+}
+
+func askPassphrase(confirm bool) ([]byte, error) {
+	passphrase, err := prompt.Password("Passphrase of the encrypted credential store:")
+	if err != nil {
+		return nil, fmt.Errorf("%w (set %s or use -secretsKeyFile for unattended runs)", err, PassphraseEnv)
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	if !confirm {
+		return passphrase, nil
+	}
+	again, err := prompt.Password("Repeat the passphrase:")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, again) {
+		return nil, errors.New("the passphrases don't match")
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,90 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"codeberg.org/snonux/gos/internal/config"
+)
+
+func TestSet(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, []byte("secret key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	passphrase := config.Passphrase
+	t.Cleanup(func() { config.Passphrase = passphrase })
+	config.Passphrase = Passphrase(keyFile)
+
+	configPath := filepath.Join(dir, "gos.json")
+	if err := os.WriteFile(configPath, []byte(`{"MastodonAccessToken": "plain-token"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := config.New(configPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := config.Args{ConfigPath: configPath, Config: conf}
+
+	if err := Set(args, []string{"NoSuchSecret", "foo"}); err == nil {
+		t.Error("expected an error for an unknown secret")
+	}
+	if err := Set(args, []string{"LinkedInTokenExpiry", "tomorrow"}); err == nil {
+		t.Error("expected an error for an expiry which isn't a Unix epoch")
+	}
+	if err := Set(args, []string{"LinkedInSecret", "linkedin-secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err = config.New(configPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Set again without unlocking the store first, it mustn't be replaced.
+	if err := Set(config.Args{ConfigPath: configPath, Config: conf}, []string{"LinkedInTokenExpiry", "42"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.UnlockStore(configPath); err != nil {
+		t.Fatal(err)
+	}
+	if conf.MastodonAccessToken != "plain-token" || conf.LinkedInSecret != "linkedin-secret" || conf.LinkedInTokenExpiry != 42 {
+		t.Errorf("expected the secrets from the store but got %+v", conf)
+	}
+	if err := Get(config.Args{ConfigPath: configPath, Config: conf}, "NoSuchSecret"); err == nil {
+		t.Error("expected an error for a missing secret")
+	}
+}
+
+func TestReadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name        string
+		content     string
+		perm        os.FileMode
+		expectError bool
+	}{
+		{"key", "secret key\n", 0o600, false},
+		{"readable", "secret key\n", 0o644, true},
+		{"empty", "\n", 0o600, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, []byte(tt.content), tt.perm); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(path, tt.perm); err != nil {
+				t.Fatal(err)
+			}
+			key, err := readKeyFile(path)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("expected error %v but got %v", tt.expectError, err)
+			}
+			if !tt.expectError && string(key) != "secret key" {
+				t.Errorf("expected the trimmed key but got %q", key)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+// Package series handles ordered multi-part entries, tagged with series:NAME:N.
+// Part N of a series is only posted once all previous parts were posted.
+package series
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"codeberg.org/snonux/gos/internal/entry"
+	"codeberg.org/snonux/gos/internal/oi"
+)
+
+// Progress is the posting progress of a series on a platform.
+type Progress struct {
+	Name   string
+	Posted int
+	Total  int
+}
+
+func (p Progress) String() string {
+	return fmt.Sprintf("%d/%d", p.Posted, p.Total)
+}
+
+// Index holds the states of the series parts in a platform directory, keyed by
+// the series name and the part number. It is built once to check many entries.
+type Index struct {
+	states map[string]map[int]entry.State
+	// The parts found in the other platform directories only, i.e. the parts
+	// which were not shared to this platform.
+	elsewhere map[string]map[int]struct{}
+}
+
+// NewIndex indexes the series parts in the platform directory and its sibling
+// platform directories.
+func NewIndex(dir string) (Index, error) {
+	ix := Index{states: make(map[string]map[int]entry.State), elsewhere: make(map[string]map[int]struct{})}
+	entries, err := seriesEntries(dir, "")
+	if err != nil {
+		return ix, err
+	}
+	for _, en := range entries {
+		name, part, _ := en.Series()
+		if ix.states[name] == nil {
+			ix.states[name] = make(map[int]entry.State)
+		}
+		// A posted copy of the part wins over a queued one.
+		if ix.states[name][part] != entry.Posted {
+			ix.states[name][part] = en.State
+		}
+	}
+
+	siblings, err := filepath.Glob(filepath.Join(filepath.Dir(dir), "*"))
+	if err != nil {
+		return ix, err
+	}
+	for _, sibling := range siblings {
+		if sibling == filepath.Clean(dir) || !isDir(sibling) {
+			continue
+		}
+		entries, err := seriesEntries(sibling, "")
+		if err != nil {
+			return ix, err
+		}
+		for _, en := range entries {
+			name, part, _ := en.Series()
+			if _, ok := ix.states[name][part]; ok {
+				continue
+			}
+			if ix.elsewhere[name] == nil {
+				ix.elsewhere[name] = make(map[int]struct{})
+			}
+			ix.elsewhere[name][part] = struct{}{}
+		}
+	}
+	return ix, nil
+}
+
+// Eligible returns whether the entry may be posted from the platform directory,
+// i.e. it is the first part of its series or the previous part was posted there.
+// Previous parts not shared to the platform (e.g. share:-mastodon) are skipped.
+func (ix Index) Eligible(en entry.Entry) bool {
+	name, part, ok := en.Series()
+	if !ok {
+		return true
+	}
+	for prev := part - 1; prev > 0; prev-- {
+		if state, ok := ix.states[name][prev]; ok {
+			return state == entry.Posted
+		}
+		if _, ok := ix.elsewhere[name][prev]; !ok {
+			return false // Not queued to any platform yet
+		}
+	}
+	return true
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// ProgressOf returns the progress of all series in the platform directory, sorted
+// by the series name.
+func ProgressOf(dir string) ([]Progress, error) {
+	entries, err := seriesEntries(dir, "")
+	if err != nil {
+		return nil, err
+	}
+	progress := make(map[string]*Progress)
+	for _, en := range entries {
+		name, _, _ := en.Series()
+		p, ok := progress[name]
+		if !ok {
+			p = &Progress{Name: name}
+			progress[name] = p
+		}
+		p.Total++
+		if en.State == entry.Posted {
+			p.Posted++
+		}
+	}
+	var results []Progress
+	for _, p := range progress {
+		results = append(results, *p)
+	}
+	slices.SortFunc(results, func(a, b Progress) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return results, nil
+}
+
+// seriesEntries returns all entries of the series in the directory. An empty
+// name returns the entries of all series.
+func seriesEntries(dir, name string) ([]entry.Entry, error) {
+	return oi.ReadDir(dir, func(file os.DirEntry) (entry.Entry, bool) {
+		if !strings.HasSuffix(file.Name(), ".queued") && !strings.HasSuffix(file.Name(), ".posted") {
+			return entry.Zero, false
+		}
+		en, err := entry.New(filepath.Join(dir, file.Name()))
+		if err != nil || en.State == entry.Inboxed {
+			return entry.Zero, false
+		}
+		seriesName, _, ok := en.Series()
+		return en, ok && (name == "" || seriesName == name)
+	})
+}
+
+// The remote post IDs of the series parts, per platform, e.g.
+// {"mastodon": {"golang:1": "113..."}}. They are used to reply to the previous part.
+type remoteIDs map[string]map[string]string
+
+func remoteIDsPath(gosDir string) string {
+	return filepath.Join(gosDir, "db", "series.json")
+}
+
+func readRemoteIDs(gosDir string) (remoteIDs, error) {
+	ids := make(remoteIDs)
+	data, err := os.ReadFile(remoteIDsPath(gosDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return ids, nil
+	}
+	if err != nil {
+		return ids, err
+	}
+	return ids, json.Unmarshal(data, &ids)
+}
+
+// RecordPost records the remote post ID of the posted entry, if it is part of a
+// series.
+func RecordPost(gosDir, platform string, en entry.Entry, remoteID string) error {
+	name, part, ok := en.Series()
+	if !ok || remoteID == "" {
+		return nil
+	}
+	ids, err := readRemoteIDs(gosDir)
+	if err != nil {
+		return err
+	}
+	if ids[platform] == nil {
+		ids[platform] = make(map[string]string)
+	}
+	ids[platform][fmt.Sprintf("%s:%d", name, part)] = remoteID
+
+	data, err := json.MarshalIndent(ids, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := oi.EnsureParentDir(remoteIDsPath(gosDir)); err != nil {
+		return err
+	}
+	return oi.WriteFile(remoteIDsPath(gosDir), string(data))
+}
+
+// PreviousRemoteID returns the remote post ID of the previous part of the series
+// the entry belongs to, if known.
+func PreviousRemoteID(gosDir, platform string, en entry.Entry) (string, bool, error) {
+	name, part, ok := en.Series()
+	if !ok || part == 1 {
+		return "", false, nil
+	}
+	ids, err := readRemoteIDs(gosDir)
+	if err != nil {
+		return "", false, err
+	}
+	id, ok := ids[platform][fmt.Sprintf("%s:%d", name, part-1)]
+	return id, ok, nil
+}
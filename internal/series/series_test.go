@@ -0,0 +1,133 @@
+package series
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"codeberg.org/snonux/gos/internal/entry"
+)
+
+func newTestDir(t *testing.T, files ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, file := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte("Hello #foo"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestEligible(t *testing.T) {
+	dir := newTestDir(t,
+		"part1.series:go:1.txt.20250101-010101.posted",
+		"part2.series:go:2.txt.20250101-010101.queued",
+		"part3.series:go:3.txt.20250101-010101.queued",
+		"other.series:rust:2.txt.20250101-010101.queued",
+		"noseries.txt.20250101-010101.queued",
+	)
+	table := map[string]bool{
+		"part2.series:go:2.txt.20250101-010101.queued":   true,
+		"part3.series:go:3.txt.20250101-010101.queued":   false,
+		"other.series:rust:2.txt.20250101-010101.queued": false, // Part 1 never posted
+
+		"noseries.txt.20250101-010101.queued": true,
+	}
+	ix, err := NewIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range table {
+		en, err := entry.New(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok := ix.Eligible(en); ok != expected {
+			t.Errorf("expected eligible=%v for %s but got %v", expected, name, ok)
+		}
+	}
+
+	progress, err := ProgressOf(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(progress) != 2 || progress[0].Name != "go" || progress[0].String() != "1/3" ||
+		progress[1].Name != "rust" || progress[1].String() != "0/1" {
+		t.Errorf("unexpected progress %v", progress)
+	}
+}
+
+func TestEligibleNotShared(t *testing.T) {
+	platformsDir := t.TempDir()
+	files := map[string][]string{
+		"mastodon": {
+			"part1.series:go:1.txt.20250101-010101.posted",
+			"part3.series:go:3.txt.20250101-010101.queued",
+			"other.series:rust:3.txt.20250101-010101.queued",
+		},
+		// Part 2 of go was only shared to LinkedIn.
+		"linkedin": {
+			"part1.series:go:1.txt.20250101-010101.posted",
+			"part2.share:linkedin.series:go:2.txt.20250101-010101.queued",
+		},
+	}
+	for platform, names := range files {
+		dir := filepath.Join(platformsDir, platform)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("Hello #foo"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	table := map[string]bool{
+		"mastodon/part3.series:go:3.txt.20250101-010101.queued":                true,
+		"mastodon/other.series:rust:3.txt.20250101-010101.queued":              false, // Parts 1 and 2 not queued anywhere
+		"linkedin/part2.share:linkedin.series:go:2.txt.20250101-010101.queued": true,
+	}
+	for name, expected := range table {
+		path := filepath.Join(platformsDir, name)
+		ix, err := NewIndex(filepath.Dir(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		en, err := entry.New(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok := ix.Eligible(en); ok != expected {
+			t.Errorf("expected eligible=%v for %s but got %v", expected, name, ok)
+		}
+	}
+}
+
+func TestRemoteIDs(t *testing.T) {
+	gosDir := t.TempDir()
+	part1, err := entry.New("part1.series:go:1.txt.20250101-010101.posted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part2, err := entry.New("part2.series:go:2.txt.20250101-010101.queued")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := PreviousRemoteID(gosDir, "mastodon", part2); err != nil || ok {
+		t.Errorf("expected no previous remote ID: %v", err)
+	}
+	if err := RecordPost(gosDir, "mastodon", part1, "42"); err != nil {
+		t.Fatal(err)
+	}
+	id, ok, err := PreviousRemoteID(gosDir, "mastodon", part2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || id != "42" {
+		t.Errorf("expected previous remote ID 42 but got '%s'", id)
+	}
+	if _, ok, _ := PreviousRemoteID(gosDir, "linkedin", part2); ok {
+		t.Error("expected no previous remote ID for linkedin")
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"codeberg.org/snonux/gos/internal/config"
 	"codeberg.org/snonux/gos/internal/entry"
+	"codeberg.org/snonux/gos/internal/link"
 )
 
 const maxLinkLength = 80
@@ -74,7 +75,8 @@ func generateGemtext(args config.Args, entries []entry.Entry, title string) (str
 			sb.WriteString("\n")
 			for _, url := range urls {
 				sb.WriteString("\n")
-				sb.WriteString(gemtextLink(args.GeminiCapsules, url, maxLinkLength))
+				// Always the clean link, as the link decoration only applies to the posts.
+				sb.WriteString(gemtextLink(args.GeminiCapsules, link.Clean(url), maxLinkLength))
 			}
 		}
 	}
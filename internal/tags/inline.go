@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"strings"
 
+	"codeberg.org/snonux/gos/internal/journal"
 	"codeberg.org/snonux/gos/internal/oi"
 	"codeberg.org/snonux/gos/internal/platforms"
 )
@@ -32,7 +33,12 @@ func InlineExtract(filePath string) (string, error) {
 	if err := oi.WriteFile(newFilePath, newContent); err != nil {
 		return "", err
 	}
-	return newFilePath, os.Remove(filePath)
+	if err := os.Remove(filePath); err != nil {
+		return "", err
+	}
+	return newFilePath, journal.Record(journal.Op{
+		Kind: journal.Extract, From: filePath, To: newFilePath, Content: content,
+	})
 }
 
 func inlineExtractTagsToFilePath(filePath, content string) (string, string, error) {
@@ -44,10 +50,15 @@ func inlineExtractTagsToFilePath(filePath, content string) (string, string, erro
 		return filePath, content, nil
 	}
 
-	parts := strings.Split(strings.TrimSuffix(filePath, filepath.Ext(filePath)), ".")
+	// Keep the extension, e.g. .md entries are rendered as Markdown.
+	ext := filepath.Ext(filePath)
+	if ext == "" {
+		ext = ".txt"
+	}
+	parts := strings.Split(strings.TrimSuffix(filePath, ext), ".")
 	parts = append(parts, tags...)
 	parts = append(parts, "extracted")
-	parts = append(parts, "txt")
+	parts = append(parts, ext[1:])
 
 	newFilePath := strings.Join(parts, ".")
 	return newFilePath, newContent, nil
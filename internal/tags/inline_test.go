@@ -33,6 +33,16 @@ func TestInlineExtractTagsToFilePath(t *testing.T) {
 	}
 }
 
+func TestInlineExtractTagsToMarkdownFilePath(t *testing.T) {
+	newFilePath, _, err := inlineExtractTagsToFilePath("./gosdir/foo.md", "prio,share:ma Hello **world**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "./gosdir/foo.prio.share:mastodon.extracted.md"; newFilePath != expected {
+		t.Errorf("expected file path '%s' but got '%s'", expected, newFilePath)
+	}
+}
+
 func TestInlineExtractTagsFromContent(t *testing.T) {
 	table := map[string][]string{
 		"foo,bar,baz blablablabla...":                {"foo", "bar", "baz"},
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/platforms"
 )
 
 // Share tags.
@@ -13,6 +14,9 @@ type Share struct {
 	Excludes []string // The platforms to exclude
 }
 
+// NewShare returns the platforms to share to according to the share tags. The
+// tags are those of the entry, i.e. the file name tags merged with the front
+// matter ones (share include and exclude lists).
 func NewShare(args config.Args, tags map[string]struct{}) (Share, error) {
 	var s Share
 
@@ -20,6 +24,11 @@ func NewShare(args config.Args, tags map[string]struct{}) (Share, error) {
 		if !strings.HasPrefix(tag, "share:") {
 			continue
 		}
+		// Canonicalise aliases, e.g. share:ma:-li -> share:mastodon:-linkedin
+		tag, err := platforms.ExpandAliases(tag)
+		if err != nil {
+			return s, err
+		}
 		for _, t := range strings.Split(tag[6:], ":") {
 			if strings.HasPrefix(t, "-") {
 				s.Excludes = append(s.Excludes, strings.ToLower(t[1:]))
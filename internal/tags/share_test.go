@@ -31,6 +31,10 @@ func TestSharePositive(t *testing.T) {
 			Includes: []string{"linkedin", "xcom"},
 			Excludes: []string{"mastodon"},
 		},
+		"./foo/bar.share:LinkedInOrg-acme:-li.txt.20240101-010101.queued": {
+			Includes: []string{"linkedinorg-acme"},
+			Excludes: []string{"linkedin"},
+		},
 		"./foo/bar/ql-e7657e8a1ab573f84ad0dbc55199e937.share:-mastodon.txt.20241018-105524.queued": {
 			Includes: []string{"linkedin"},
 			Excludes: []string{"mastodon"},
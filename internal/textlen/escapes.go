@@ -0,0 +1,60 @@
+package textlen
+
+import (
+	"regexp"
+	"strings"
+)
+
+// A mention in the LinkedIn little text format, e.g. @[Paul](urn:li:person:123).
+// Reserved characters of the name are escaped, e.g. @[Acme \(Europe\)](...).
+var linkedInMentionRE = regexp.MustCompile(`@\[((?:[^\[\]\\]|\\.)+)\]\((urn:li:(?:person|organization):[^()\s]+)\)`)
+
+// An escaped character of the LinkedIn little text format.
+var linkedInEscapeRE = regexp.MustCompile(`\\(.)`)
+
+// EscapeLinkedIn escapes the reserved characters of the LinkedIn little text format.
+// Mentions are kept as they are, so that LinkedIn links them to the member.
+// https://learn.microsoft.com/en-us/linkedin/marketing/community-management/shares/little-text-format?view=li-lms-2024-01#language-grammar
+func EscapeLinkedIn(input string) string {
+	var builder strings.Builder
+	last := 0
+	for _, loc := range linkedInMentionRE.FindAllStringIndex(input, -1) {
+		builder.WriteString(escapeLinkedIn(input[last:loc[0]]))
+		builder.WriteString(input[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	builder.WriteString(escapeLinkedIn(input[last:]))
+	return builder.String()
+}
+
+func escapeLinkedIn(input string) string {
+	var builder strings.Builder
+
+	reservedChars := map[rune]string{
+		'|': "\\|",
+		'{': "\\{",
+		'}': "\\}",
+		// '@': "\\@",
+		'[': "\\[",
+		']': "\\]",
+		'(': "\\(",
+		')': "\\)",
+		'<': "\\<",
+		'>': "\\>",
+		//'#':  "\\#",
+		'\\': "\\\\",
+		'*':  "\\*",
+		'_':  "\\_",
+		'~':  "\\~",
+	}
+
+	for _, char := range input {
+		if escapeSeq, ok := reservedChars[char]; ok {
+			builder.WriteString(escapeSeq)
+		} else {
+			builder.WriteRune(char)
+		}
+	}
+
+	return builder.String()
+}
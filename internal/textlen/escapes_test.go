@@ -0,0 +1,25 @@
+package textlen
+
+import (
+	"testing"
+)
+
+func TestLinkedInEscapes(t *testing.T) {
+	var (
+		input    = `This is a test message with special characters: " {} @ [] () <> # \ * _ ~ |`
+		expected = `This is a test message with special characters: " \{\} @ \[\] \(\) \<\> # \\ \* \_ \~ \|`
+	)
+	if escaped := EscapeLinkedIn(input); escaped != expected {
+		t.Errorf("expected '%s' but got '%s'", expected, escaped)
+	}
+}
+
+func TestLinkedInEscapesMentions(t *testing.T) {
+	var (
+		input    = `Thanks @[Paul Buetow](urn:li:person:a1B2) and @[Acme \(EU\)](urn:li:organization:42) (and @[not](a mention))`
+		expected = `Thanks @[Paul Buetow](urn:li:person:a1B2) and @[Acme \(EU\)](urn:li:organization:42) \(and @\[not\]\(a mention\)\)`
+	)
+	if escaped := EscapeLinkedIn(input); escaped != expected {
+		t.Errorf("expected '%s' but got '%s'", expected, escaped)
+	}
+}
@@ -0,0 +1,63 @@
+// Package textlen counts the length of a text the way the social media platforms
+// do, so that entries are not rejected for the wrong reason. E.g. an emoji is one
+// character and not four bytes.
+package textlen
+
+import (
+	"regexp"
+	"unicode/utf8"
+
+	"codeberg.org/snonux/gos/internal/config"
+	"github.com/rivo/uniseg"
+)
+
+// Mastodon counts every URL as 23 characters, no matter how long it actually is.
+const mastodonURLLength = 23
+
+var (
+	urlRE = regexp.MustCompile(`(?:https?|ftp)://\S+`)
+	// Mentions of remote accounts only count with the user name, e.g. @paul@foo.zone
+	// counts as @paul.
+	mastodonMentionRE = regexp.MustCompile(`(^|[^\w/])(@\w+)@[\w.-]+\w`)
+)
+
+// Count returns the length of the text as counted by the platform.
+func Count(platform, text string) int {
+	switch config.Network(platform) {
+	case "mastodon":
+		return Mastodon(text)
+	case "linkedin":
+		return LinkedIn(text)
+	default:
+		// E.g. Bluesky counts grapheme clusters.
+		return Graphemes(text)
+	}
+}
+
+// Graphemes returns the number of user-perceived characters (grapheme clusters).
+func Graphemes(text string) int {
+	return uniseg.GraphemeClusterCount(text)
+}
+
+// Mastodon returns the number of grapheme clusters, with every URL counting as
+// 23 characters and remote mentions counting without their domain.
+func Mastodon(text string) int {
+	urls := len(urlRE.FindAllString(text, -1))
+	text = urlRE.ReplaceAllString(text, "")
+	text = mastodonMentionRE.ReplaceAllString(text, "$1$2")
+	return Graphemes(text) + urls*mastodonURLLength
+}
+
+// LinkedIn returns the number of characters after escaping the reserved
+// characters of the LinkedIn little text format. Mentions count with the name
+// displayed.
+func LinkedIn(text string) int {
+	var count int
+	last := 0
+	for _, loc := range linkedInMentionRE.FindAllStringSubmatchIndex(text, -1) {
+		count += utf8.RuneCountInString(escapeLinkedIn(text[last:loc[0]]))
+		count += utf8.RuneCountInString(linkedInEscapeRE.ReplaceAllString(text[loc[2]:loc[3]], "$1"))
+		last = loc[1]
+	}
+	return count + utf8.RuneCountInString(escapeLinkedIn(text[last:]))
+}
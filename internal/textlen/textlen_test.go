@@ -0,0 +1,30 @@
+package textlen
+
+import "testing"
+
+func TestCount(t *testing.T) {
+	table := []struct {
+		platform string
+		text     string
+		expected int
+	}{
+		{"bluesky", "Hello", 5},
+		{"bluesky", "Grüße 👋🏽", 7},
+		{"bluesky", "🇩🇪 flag", 6},
+		{"mastodon", "Grüße 👋🏽", 7},
+		{"mastodon", "Read https://foo.zone/gemfeed/2025-01-01-a-very-long-blog-post-title.html", 5 + 23},
+		{"mastodon", "Hi @paul@foo.zone and @bob", 3 + 5 + 5 + 4},
+		{"mastodon", "Mail me at paul@foo.zone", 24},
+		{"linkedin", "Hello", 5},
+		{"linkedin", "(foo) [bar]", 15},
+		{"linkedin", "Grüße", 5},
+		{"linkedin", "Hi @[Paul](urn:li:person:a1B2)!", 8},
+		{"linkedin", "Hi @[Acme \\(EU\\)](urn:li:organization:42)!", 13},
+		{"linkedinorg-acme", "(foo)", 7},
+	}
+	for _, tt := range table {
+		if got := Count(tt.platform, tt.text); got != tt.expected {
+			t.Errorf("expected %d for '%s' on %s but got %d", tt.expected, tt.text, tt.platform, got)
+		}
+	}
+}
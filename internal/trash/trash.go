@@ -0,0 +1,309 @@
+// Package trash manages the trashbin of gos. Processed and deleted entries are
+// kept in the trashbin for a while, so that they can be restored if needed.
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/colour"
+	"codeberg.org/snonux/gos/internal/config"
+	"codeberg.org/snonux/gos/internal/entry"
+	"codeberg.org/snonux/gos/internal/journal"
+	"codeberg.org/snonux/gos/internal/oi"
+	"codeberg.org/snonux/gos/internal/timestamp"
+)
+
+const suffix = ".trash"
+
+// The sidecar file of an item, e.g. foo.txt.trash.time, records when the item was
+// trashed, so that the modification time of the entry is kept.
+const timeSuffix = ".time"
+
+var errAmbiguous = errors.New("ambiguous trash item name")
+
+// Trashbin layout, relative to ./db/trashbin:
+//
+//	./db/foo.txt.STAMP.queued             <-> ./foo.txt.STAMP.trash
+//	./db/platforms/PLATFORM/foo.txt.STAMP.queued <-> ./platforms/PLATFORM/foo.txt.STAMP.queued.trash
+//	./foo.txt (inbox)                     <-> ./inbox/foo.txt.trash
+type Trashbin struct {
+	gosDir    string
+	dir       string
+	retention time.Duration
+}
+
+// Item is an entry in the trashbin.
+type Item struct {
+	Path       string    // Path in the trashbin
+	OriginPath string    // Path to restore the item to
+	Trashed    time.Time // When the item was moved into the trashbin
+}
+
+func New(args config.Args) Trashbin {
+	return Trashbin{
+		gosDir:    args.GosDir,
+		dir:       filepath.Join(args.GosDir, "db", "trashbin"),
+		retention: args.TrashRetention,
+	}
+}
+
+// Name returns the entry name of the item, e.g. foo.prio.txt
+func (it Item) Name() string {
+	en, err := entry.New(it.OriginPath)
+	if err != nil {
+		return filepath.Base(it.OriginPath)
+	}
+	return en.Name()
+}
+
+// Platform returns the platform the item was trashed from, or an empty string if
+// it wasn't trashed from a platform queue.
+func (it Item) Platform() string {
+	parts := strings.Split(filepath.ToSlash(it.Path), "/")
+	if len(parts) > 2 && parts[len(parts)-3] == "platforms" {
+		return parts[len(parts)-2]
+	}
+	return ""
+}
+
+// Move moves the file into the trashbin and returns the path in the trashbin. A
+// file trashed again gets a counter, e.g. foo.txt.2.trash, so that the earlier
+// copy and its sidecar file are kept.
+func (t Trashbin) Move(filePath string) (string, error) {
+	trashPath, err := t.trashPath(filePath)
+	if err != nil {
+		return "", err
+	}
+	trashPath = unique(trashPath)
+	colour.Infofln("Trashing %s -> %s", filePath, trashPath)
+	if err := oi.Rename(filePath, trashPath); err != nil {
+		return "", err
+	}
+	if err := journal.Record(journal.Op{Kind: journal.Trash, From: filePath, To: trashPath}); err != nil {
+		return "", err
+	}
+	// Tells when the file was trashed (used for purging).
+	now := []byte(time.Now().Format(time.RFC3339Nano))
+	return trashPath, os.WriteFile(trashPath+timeSuffix, now, 0644)
+}
+
+// Returns the trash path, with a counter if it or its sidecar file is taken.
+func unique(trashPath string) string {
+	path := trashPath
+	for n := 2; taken(path) || taken(path+timeSuffix); n++ {
+		path = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(trashPath, suffix), n, suffix)
+	}
+	return path
+}
+
+func taken(path string) bool {
+	_, err := os.Lstat(path)
+	return !os.IsNotExist(err)
+}
+
+// Returns when the item was trashed. Items trashed by older versions of gos have
+// got no sidecar file, but their modification time was set instead.
+func trashed(trashPath string, info fs.FileInfo) time.Time {
+	data, err := os.ReadFile(trashPath + timeSuffix)
+	if err != nil {
+		return info.ModTime()
+	}
+	trashed, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+	if err != nil {
+		return info.ModTime()
+	}
+	return trashed
+}
+
+// Removes the sidecar file of the item, if any.
+func removeTime(trashPath string) error {
+	if err := os.Remove(trashPath + timeSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List returns all items in the trashbin, the most recently trashed first.
+func (t Trashbin) List() ([]Item, error) {
+	var items []Item
+	if err := oi.EnsureDir(t.dir); err != nil {
+		return items, err
+	}
+	err := filepath.WalkDir(t.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, suffix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		originPath, err := t.originPath(path)
+		if err != nil {
+			colour.Infoln(err)
+			return nil
+		}
+		items = append(items, Item{Path: path, OriginPath: originPath, Trashed: trashed(path, info)})
+		return nil
+	})
+	slices.SortFunc(items, func(a, b Item) int {
+		return b.Trashed.Compare(a.Trashed)
+	})
+	return items, err
+}
+
+// Restore moves all items matching the entry name back to where they came from.
+// The name can also be the item's path relative to the trashbin.
+func (t Trashbin) Restore(name string) ([]Item, error) {
+	items, err := t.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		matching []Item
+		names    = make(map[string]struct{})
+	)
+	for _, it := range items {
+		// Only the most recently trashed copy of a file is restored.
+		if slices.ContainsFunc(matching, func(m Item) bool { return m.OriginPath == it.OriginPath }) {
+			continue
+		}
+		rel, _ := filepath.Rel(t.dir, it.Path)
+		en, err := entry.New(it.OriginPath)
+		if rel == name || filepath.Base(it.Path) == name || (err == nil && en.Matches(name)) {
+			matching = append(matching, it)
+			names[it.Name()] = struct{}{}
+		}
+	}
+	switch {
+	case len(matching) == 0:
+		return nil, fmt.Errorf("%w: '%s' in trashbin", oi.ErrNotFound, name)
+	case len(names) > 1:
+		var keys []string
+		for key := range names {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		return nil, fmt.Errorf("%w '%s', matches: %v", errAmbiguous, name, keys)
+	}
+
+	for _, it := range matching {
+		if _, err := os.Stat(it.OriginPath); err == nil {
+			return nil, fmt.Errorf("unable to restore %s as %s already exists", it.Path, it.OriginPath)
+		}
+		colour.Infofln("Restoring %s -> %s", it.Path, it.OriginPath)
+		if err := oi.Rename(it.Path, it.OriginPath); err != nil {
+			return nil, err
+		}
+		// The sidecar file stays, in case the restore is undone. Purge cleans it up.
+		if err := journal.Record(journal.Op{Kind: journal.Restore, From: it.Path, To: it.OriginPath}); err != nil {
+			return nil, err
+		}
+	}
+	return matching, nil
+}
+
+// Purge deletes all items which are in the trashbin for longer than the retention
+// period. A retention period of zero or less keeps all items forever.
+func (t Trashbin) Purge() error {
+	if t.retention <= 0 {
+		return nil
+	}
+	items, err := t.List()
+	if err != nil {
+		return err
+	}
+	olderThan := time.Now().Add(-t.retention)
+	for _, it := range items {
+		if it.Trashed.Before(olderThan) {
+			colour.Infoln("Cleaning up", it.Path)
+			if err := journal.RecordPurge(it.Path); err != nil {
+				return err
+			}
+			if err := removeTime(it.Path); err != nil {
+				return err
+			}
+		}
+	}
+	return t.purgeTimes(olderThan)
+}
+
+// Deletes the sidecar files older than the retention period whose items aren't
+// in the trashbin anymore, e.g. because they were restored.
+func (t Trashbin) purgeTimes(olderThan time.Time) error {
+	return filepath.WalkDir(t.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, suffix+timeSuffix) {
+			return err
+		}
+		trashPath := strings.TrimSuffix(path, timeSuffix)
+		if _, err := os.Stat(trashPath); !os.IsNotExist(err) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if trashed(trashPath, info).Before(olderThan) {
+			return os.Remove(path)
+		}
+		return nil
+	})
+}
+
+func (t Trashbin) trashPath(filePath string) (string, error) {
+	dbDir := filepath.Join(t.gosDir, "db")
+	base := filepath.Base(filePath)
+
+	switch dir := filepath.Dir(filePath); {
+	case dir == dbDir && strings.HasSuffix(base, ".queued"):
+		return filepath.Join(t.dir, strings.TrimSuffix(base, ".queued")+suffix), nil
+	case filepath.Dir(dir) == filepath.Join(dbDir, "platforms"):
+		return filepath.Join(t.dir, "platforms", filepath.Base(dir), base+suffix), nil
+	case dir == filepath.Clean(t.gosDir):
+		return filepath.Join(t.dir, "inbox", base+suffix), nil
+	default:
+		return "", fmt.Errorf("%s is not an entry in %s", filePath, t.gosDir)
+	}
+}
+
+func (t Trashbin) originPath(trashPath string) (string, error) {
+	name := strings.TrimSuffix(trashPath, suffix)
+	// Drops the counter of a file trashed again, e.g. foo.txt.2
+	if ext := filepath.Ext(name); len(ext) > 1 {
+		if _, err := strconv.Atoi(ext[1:]); err == nil {
+			name = strings.TrimSuffix(name, ext)
+		}
+	}
+	rel, err := filepath.Rel(t.dir, name)
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+
+	switch {
+	case len(parts) == 1:
+		// Processed entries from ./db/*.txt.STAMP.queued must end with a STAMP.
+		nameParts := strings.Split(parts[0], ".")
+		if _, err := timestamp.Parse(nameParts[len(nameParts)-1]); err != nil {
+			return "", fmt.Errorf("unexpected item %s in trashbin: %w", trashPath, err)
+		}
+		return filepath.Join(t.gosDir, "db", parts[0]+".queued"), nil
+	case len(parts) == 2 && parts[0] == "inbox":
+		return filepath.Join(t.gosDir, parts[1]), nil
+	case len(parts) == 3 && parts[0] == "platforms":
+		return filepath.Join(t.gosDir, "db", "platforms", parts[1], parts[2]), nil
+	default:
+		return "", fmt.Errorf("unexpected item %s in trashbin", trashPath)
+	}
+}
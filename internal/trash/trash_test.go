@@ -0,0 +1,164 @@
+package trash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"codeberg.org/snonux/gos/internal/config"
+)
+
+func TestMoveAndRestore(t *testing.T) {
+	gosDir := t.TempDir()
+	trashbin := New(config.Args{GosDir: gosDir})
+
+	paths := []string{
+		filepath.Join(gosDir, "foo.prio.txt"),
+		filepath.Join(gosDir, "db", "bar.txt.20250101-010101.queued"),
+		filepath.Join(gosDir, "db", "platforms", "mastodon", "baz.txt.20250101-010101.queued"),
+	}
+	modTime := time.Date(2025, 1, 1, 1, 1, 1, 0, time.UTC)
+	for _, path := range paths {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("Hello #world"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := trashbin.Move(path); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("expected %s to be moved into the trashbin", path)
+		}
+	}
+
+	items, err := trashbin.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != len(paths) {
+		t.Fatalf("expected %d items in the trashbin but got %v", len(paths), items)
+	}
+	for _, it := range items {
+		if it.Name() == "baz.txt" && it.Platform() != "mastodon" {
+			t.Errorf("expected platform mastodon for %s but got '%s'", it.Path, it.Platform())
+		}
+	}
+
+	for _, name := range []string{"foo", "bar.txt", "baz"} {
+		if _, err := trashbin.Restore(name); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Errorf("expected %s to be restored: %v", path, err)
+			continue
+		}
+		if !info.ModTime().Equal(modTime) {
+			t.Errorf("expected %s to keep its modification time %v but got %v", path, modTime, info.ModTime())
+		}
+	}
+	if _, err := trashbin.Restore("foo"); err == nil {
+		t.Error("expected an error restoring an entry not in the trashbin")
+	}
+}
+
+func TestMoveTwice(t *testing.T) {
+	gosDir := t.TempDir()
+	trashbin := New(config.Args{GosDir: gosDir})
+	path := filepath.Join(gosDir, "foo.txt")
+
+	var trashPaths []string
+	for _, content := range []string{"first", "second"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		trashPath, err := trashbin.Move(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trashPaths = append(trashPaths, trashPath)
+	}
+	if trashPaths[0] == trashPaths[1] {
+		t.Fatalf("expected the second copy not to overwrite the first one at %s", trashPaths[0])
+	}
+	for i, content := range []string{"first", "second"} {
+		data, err := os.ReadFile(trashPaths[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("expected %q in %s but got %q", content, trashPaths[i], data)
+		}
+		if _, err := os.Stat(trashPaths[i] + timeSuffix); err != nil {
+			t.Errorf("expected the sidecar file of %s: %v", trashPaths[i], err)
+		}
+	}
+
+	items, err := trashbin.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, it := range items {
+		if it.OriginPath != path {
+			t.Errorf("expected %s to be restored to %s but got %s", it.Path, path, it.OriginPath)
+		}
+	}
+
+	// The most recently trashed copy is restored.
+	if _, err := trashbin.Restore("foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "second" {
+		t.Errorf("expected the second copy to be restored but got %q (%v)", data, err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	gosDir := t.TempDir()
+	trashbin := New(config.Args{GosDir: gosDir, TrashRetention: 24 * time.Hour})
+
+	for _, name := range []string{"old.txt", "new.txt"} {
+		path := filepath.Join(gosDir, name)
+		if err := os.WriteFile(path, []byte("Hello #world"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		trashPath, err := trashbin.Move(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name == "old.txt" {
+			past := time.Now().Add(-48 * time.Hour).Format(time.RFC3339Nano)
+			if err := os.WriteFile(trashPath+timeSuffix, []byte(past), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// The sidecar file of an item restored a long time ago.
+	orphan := filepath.Join(gosDir, "db", "trashbin", "inbox", "restored.txt.trash.time")
+	if err := os.WriteFile(orphan, []byte(time.Now().Add(-48*time.Hour).Format(time.RFC3339Nano)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := trashbin.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("expected the orphaned sidecar file to be purged but got %v", err)
+	}
+	items, err := trashbin.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Name() != "new.txt" {
+		t.Errorf("expected only new.txt to be left in the trashbin but got %v", items)
+	}
+}